@@ -0,0 +1,170 @@
+package pantheon
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// probeRequest asks a node agent to probe another node
+type probeRequest struct {
+	ID          string `json:"id"`
+	Address     string `json:"address"`
+	Path        string `json:"path"`
+	HealthCheck string `json:"health_check"`
+}
+
+// probeResponse is the result of a probe made by a node agent
+type probeResponse struct {
+	Success bool   `json:"success"`
+	RTT     int64  `json:"rtt_us"`
+	Error   string `json:"error,omitempty"`
+}
+
+// MemberLookup returns the cluster member with the given ID, nil if there is none
+type MemberLookup func(id string) (*Member, error)
+
+// Agent serves the endpoint Pantheon uses for indirect probes
+// Mount it in each node's HTTP server at the indirect probe path (by default
+// "pantheon/probe"). When Pantheon cannot reach a node directly it asks the
+// agents of other nodes to run the node's health check, so a flaky path
+// between Pantheon and one node does not get the node evicted.
+// An agent makes requests on behalf of its callers, so it must be restricted
+// with WithMemberLookup, WithToken or both; an agent with neither rejects every
+// request.
+type Agent struct {
+	checkers map[string]HealthChecker
+	timeout  time.Duration
+	// members; resolves probe targets by ID, nil trusts the address in the request
+	members MemberLookup
+	// token; the shared token callers must present, empty disables the check
+	token string
+}
+
+// NewAgent creates an agent with the built-in HTTP, TCP and gRPC checkers
+func NewAgent() *Agent {
+	return &Agent{
+		checkers: map[string]HealthChecker{
+			HealthCheckHTTP: &HTTPChecker{},
+			HealthCheckTCP:  TCPChecker{},
+			HealthCheckGRPC: &GRPCChecker{},
+		},
+		timeout: 5 * time.Second,
+	}
+}
+
+// WithHealthChecker registers a health checker under the given name
+// Register the same checkers as the Pantheon instances asking for probes.
+func (a *Agent) WithHealthChecker(name string, checker HealthChecker) *Agent {
+	a.checkers[name] = checker
+	return a
+}
+
+// WithTimeout sets the maximum duration of a probe
+func (a *Agent) WithTimeout(timeout time.Duration) *Agent {
+	a.timeout = timeout
+	return a
+}
+
+// WithMemberLookup only probes current cluster members
+// Targets are looked up by ID, and their stored address, path and health check
+// are used instead of the ones in the request. Pantheon.GetMember is a lookup.
+func (a *Agent) WithMemberLookup(lookup MemberLookup) *Agent {
+	a.members = lookup
+	return a
+}
+
+// WithToken requires callers to send the given token as a bearer token
+// Configure Pantheon with the same token (see Options.WithIndirectProbeToken).
+func (a *Agent) WithToken(token string) *Agent {
+	a.token = token
+	return a
+}
+
+// ServeHTTP implements the http.Handler interface
+func (a *Agent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if a.members == nil && a.token == "" {
+		http.Error(w, "agent has no member lookup or token configured", http.StatusForbidden)
+		return
+	}
+
+	if a.token != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var req probeRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&req); err != nil {
+		http.Error(w, "invalid probe request", http.StatusBadRequest)
+		return
+	}
+
+	target := &Member{
+		ID:          req.ID,
+		Address:     req.Address,
+		Path:        req.Path,
+		HealthCheck: req.HealthCheck,
+	}
+
+	if a.members != nil {
+		member, err := a.members(req.ID)
+		if err != nil {
+			http.Error(w, "error looking up member", http.StatusInternalServerError)
+			return
+		}
+		if member == nil {
+			http.Error(w, "not a cluster member", http.StatusForbidden)
+			return
+		}
+		target = member
+	}
+
+	name := target.HealthCheck
+	if name == "" {
+		name = HealthCheckHTTP
+	}
+
+	checker, ok := a.checkers[name]
+	if !ok {
+		http.Error(w, "unknown health check", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if a.timeout > 0 {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, a.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := checker.Check(ctx, &Member{
+		ID:          target.ID,
+		Address:     target.Address,
+		Path:        target.Path,
+		HealthCheck: name,
+	})
+
+	resp := probeResponse{
+		Success: err == nil,
+		RTT:     time.Since(start).Microseconds(),
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,137 @@
+package pantheon
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingServer answers health checks after delay and counts the requests it receives
+func countingServer(t *testing.T, delay time.Duration) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+
+	var hits atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &hits
+}
+
+// memberLookup resolves members from a fixed set
+func memberLookup(members ...*Member) MemberLookup {
+	return func(id string) (*Member, error) {
+		for _, member := range members {
+			if member.ID == id {
+				return member, nil
+			}
+		}
+		return nil, nil
+	}
+}
+
+// callAgent sends a probe request to an agent and decodes the response
+func callAgent(t *testing.T, agent *Agent, token string, req probeRequest) (int, probeResponse) {
+	t.Helper()
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/pantheon/probe", bytes.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	agent.ServeHTTP(w, r)
+
+	var resp probeResponse
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w.Code, resp
+}
+
+func TestAgentProbesClusterMembers(t *testing.T) {
+	target, targetHits := countingServer(t, 0)
+	other, otherHits := countingServer(t, 0)
+
+	agent := NewAgent().WithMemberLookup(memberLookup(&Member{ID: "node-1", Address: target.URL, State: MemberAlive}))
+
+	// The stored address is probed, whatever address the request names
+	code, resp := callAgent(t, agent, "", probeRequest{ID: "node-1", Address: other.URL})
+	if code != http.StatusOK || !resp.Success {
+		t.Fatalf("expected a successful probe, got %d %+v", code, resp)
+	}
+	if targetHits.Load() != 1 || otherHits.Load() != 0 {
+		t.Errorf("expected only the member to be probed, member %d, other %d", targetHits.Load(), otherHits.Load())
+	}
+
+	// Unknown IDs are rejected without any request
+	code, _ = callAgent(t, agent, "", probeRequest{ID: "intruder", Address: other.URL})
+	if code != http.StatusForbidden {
+		t.Errorf("expected 403 for an unknown member, got %d", code)
+	}
+	if otherHits.Load() != 0 {
+		t.Error("agent probed a non-member")
+	}
+}
+
+func TestAgentRequiresToken(t *testing.T) {
+	target, hits := countingServer(t, 0)
+	agent := NewAgent().WithToken("secret")
+	req := probeRequest{ID: "node-1", Address: target.URL}
+
+	for _, token := range []string{"", "wrong"} {
+		if code, _ := callAgent(t, agent, token, req); code != http.StatusUnauthorized {
+			t.Errorf("token %q: expected 401, got %d", token, code)
+		}
+	}
+	if hits.Load() != 0 {
+		t.Fatal("agent probed without a valid token")
+	}
+
+	if code, resp := callAgent(t, agent, "secret", req); code != http.StatusOK || !resp.Success {
+		t.Fatalf("expected a successful probe, got %d %+v", code, resp)
+	}
+}
+
+func TestAgentRejectsRequestsWhenUnrestricted(t *testing.T) {
+	target, hits := countingServer(t, 0)
+
+	code, _ := callAgent(t, NewAgent(), "", probeRequest{ID: "node-1", Address: target.URL})
+	if code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", code)
+	}
+	if hits.Load() != 0 {
+		t.Error("unrestricted agent made a request")
+	}
+}
+
+func TestAgentTimesOutProbes(t *testing.T) {
+	target, _ := countingServer(t, time.Second)
+	agent := NewAgent().
+		WithMemberLookup(memberLookup(&Member{ID: "node-1", Address: target.URL, State: MemberAlive})).
+		WithTimeout(50 * time.Millisecond)
+
+	start := time.Now()
+	code, resp := callAgent(t, agent, "", probeRequest{ID: "node-1"})
+	if code != http.StatusOK || resp.Success {
+		t.Fatalf("expected a failed probe, got %d %+v", code, resp)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("probe took %s despite the timeout", elapsed)
+	}
+}
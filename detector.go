@@ -0,0 +1,184 @@
+package pantheon
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// HeartbeatResult is the outcome of one health check of a node
+type HeartbeatResult struct {
+	// Success; whether the node passed the check
+	Success bool
+	// At; when the check completed
+	At time.Time
+	// RTT; how long the check took, zero if the check does not measure it
+	RTT time.Duration
+}
+
+// FailureDetector decides from heartbeat results whether a node is alive, suspect or dead
+type FailureDetector interface {
+	// Observe records a heartbeat result for a node and returns the state the
+	// node should be in: MemberAlive, MemberSuspect or MemberDead
+	Observe(ctx context.Context, node *Member, result HeartbeatResult) (MemberState, error)
+}
+
+// counterDetector marks a node suspect on its first failed heartbeat and dead
+// after maxFailures failed heartbeats, or the node's own max failures if set
+type counterDetector struct {
+	storage     *Storage
+	maxFailures int
+}
+
+// Observe implements the FailureDetector interface
+func (d *counterDetector) Observe(ctx context.Context, node *Member, result HeartbeatResult) (MemberState, error) {
+	if result.Success {
+		return MemberAlive, nil
+	}
+
+	// Increment the failure count, including this failure
+	failures, err := d.storage.IncrementHeartbeatFailures(ctx, node.ID)
+	if err != nil {
+		return "", fmt.Errorf("error incrementing failure count: %w", err)
+	}
+
+	maxFailures := d.maxFailures
+	if node.Heartbeat.MaxFailures > 0 {
+		maxFailures = node.Heartbeat.MaxFailures
+	}
+
+	if failures >= int64(maxFailures) {
+		return MemberDead, nil
+	}
+
+	return MemberSuspect, nil
+}
+
+// phiAccrualDetector implements the phi accrual failure detector
+// (Hayashibara et al.): instead of counting failures it tracks the distribution
+// of intervals between successful heartbeats and computes phi, the suspicion
+// that the node has failed given how long it has been silent. Jittery but live
+// nodes widen the distribution and are suspected later.
+type phiAccrualDetector struct {
+	storage *Storage
+	// suspectPhi; the phi at which a node becomes suspect
+	suspectPhi float64
+	// deadPhi; the phi at which a node is considered dead
+	deadPhi float64
+	// windowSize; the number of intervals and RTTs kept per node
+	windowSize int
+	// minStdDev; the lower bound of the interval standard deviation
+	// 0 uses half of the node's heartbeat interval
+	minStdDev time.Duration
+	// expectedInterval; the interval assumed before any has been observed,
+	// unless the node has its own heartbeat interval
+	expectedInterval time.Duration
+}
+
+// Observe implements the FailureDetector interface
+func (d *phiAccrualDetector) Observe(ctx context.Context, node *Member, result HeartbeatResult) (MemberState, error) {
+	window, err := d.storage.GetHeartbeatWindow(ctx, node.ID)
+	if err != nil {
+		return "", err
+	}
+
+	if result.Success {
+		if !window.LastArrival.IsZero() {
+			window.Intervals = appendBounded(window.Intervals, result.At.Sub(window.LastArrival), d.windowSize)
+		}
+		if result.RTT > 0 {
+			window.RTTs = appendBounded(window.RTTs, result.RTT, d.windowSize)
+		}
+		window.LastArrival = result.At
+
+		if err := d.storage.SaveHeartbeatWindow(ctx, node.ID, window); err != nil {
+			return "", err
+		}
+
+		return MemberAlive, nil
+	}
+
+	// Nodes that never answered have been silent since they joined
+	lastArrival := window.LastArrival
+	if lastArrival.IsZero() {
+		joinedAt, err := strconv.ParseInt(node.JoinedAt, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("error parsing joined_at: %w", err)
+		}
+		lastArrival = time.Unix(joinedAt, 0)
+	}
+
+	expected := d.expectedInterval
+	if node.Heartbeat.Interval > 0 {
+		expected = node.Heartbeat.Interval
+	}
+
+	phi := d.phi(window.Intervals, result.At.Sub(lastArrival), expected)
+
+	switch {
+	case phi >= d.deadPhi:
+		return MemberDead, nil
+	case phi >= d.suspectPhi:
+		return MemberSuspect, nil
+	default:
+		// A single late heartbeat is not enough to change the node's state
+		return node.State, nil
+	}
+}
+
+// phi returns the suspicion level after elapsed time without a heartbeat,
+// given the observed intervals between heartbeats and the expected interval
+// It uses the logistic approximation of the normal distribution's tail.
+func (d *phiAccrualDetector) phi(intervals []time.Duration, elapsed, expected time.Duration) float64 {
+	mean := float64(expected)
+	variance := 0.0
+
+	if len(intervals) > 0 {
+		sum := 0.0
+		for _, interval := range intervals {
+			sum += float64(interval)
+		}
+		mean = sum / float64(len(intervals))
+
+		for _, interval := range intervals {
+			variance += (float64(interval) - mean) * (float64(interval) - mean)
+		}
+		variance /= float64(len(intervals))
+	}
+
+	minStdDev := d.minStdDev
+	if minStdDev == 0 {
+		minStdDev = expected / 2
+	}
+
+	stdDev := math.Max(math.Sqrt(variance), float64(minStdDev))
+
+	y := (float64(elapsed) - mean) / stdDev
+	e := math.Exp(-y * (1.5976 + 0.070566*y*y))
+	if float64(elapsed) > mean {
+		return -math.Log10(e / (1 + e))
+	}
+	return -math.Log10(1 - 1/(1+e))
+}
+
+// HeartbeatWindow is the sliding window of heartbeat timings kept for a node
+// by the phi accrual failure detector
+type HeartbeatWindow struct {
+	// LastArrival; when the last successful heartbeat completed
+	LastArrival time.Time
+	// Intervals; the most recent intervals between successful heartbeats
+	Intervals []time.Duration
+	// RTTs; the most recent round-trip times of successful heartbeats
+	RTTs []time.Duration
+}
+
+// appendBounded appends value to values, dropping the oldest values beyond size
+func appendBounded(values []time.Duration, value time.Duration, size int) []time.Duration {
+	values = append(values, value)
+	if len(values) > size {
+		values = values[len(values)-size:]
+	}
+	return values
+}
@@ -3,17 +3,24 @@ package pantheon
 import (
 	"fmt"
 
+	"github.com/fleetcontrolsio/pantheon/pkg/hashring"
 	"github.com/redis/go-redis/v9"
 )
 
 // Distribute distributes keys to the nodes in the cluster
 // This should be called after a nodes has joined or left the cluster to
 // rebalance the keys to available nodes
+// In partition mode no per-key mappings are written; the partition table is
+// rebalanced instead.
 func (c *Pantheon) Distribute(keys []string) error {
 	if !c.started {
 		return fmt.Errorf("cluster not started")
 	}
 
+	if c.partitioner != nil {
+		return c.RebalancePartitions()
+	}
+
 	// Check if hashring is available
 	if c.hashRing == nil {
 		return fmt.Errorf("hash ring not initialized")
@@ -26,16 +33,59 @@ func (c *Pantheon) Distribute(keys []string) error {
 
 	fmt.Printf("Distributing %d keys using consistent hashing\n", len(keys))
 
+	// In bounded-load mode the ring needs the current number of keys per node
+	loadAware, bounded := c.boundedLoadRing()
+	if bounded {
+		if err := c.refreshRingLoads(loadAware); err != nil {
+			return err
+		}
+	}
+
+	// Work against a single snapshot of the ring when it supports them, and
+	// find the hash ranges that changed owner since the last distribution
+	current, previousRun, moved := c.distributionSnapshots(bounded)
+
 	// Use consistent hashing to distribute keys
 	distribution := make(map[string][]string)
 
 	for _, key := range keys {
+		keyMapKey := c.storage.makeKey("keymap", key)
+
+		// Look up the current owner so a reassigned key is not counted twice
+		previous, err := c.storage.redis.Get(c.ctx, keyMapKey).Result()
+		if err != nil && err != redis.Nil {
+			return fmt.Errorf("error getting node for key %s: %w", key, err)
+		}
+
+		// Keys mapped by the last distribution outside the moved ranges keep their owner
+		if previousRun != nil && previous != "" {
+			if _, ok := moved.Lookup(current.Hash(key)); !ok {
+				if owner, err := previousRun.GetNode(key); err == nil && owner.ID == previous {
+					distribution[previous] = append(distribution[previous], key)
+					continue
+				}
+			}
+		}
+
+		if bounded && previous != "" {
+			loadAware.AddLoad(previous, -1)
+		}
+
 		// Get the node for this key using consistent hashing
-		node, err := c.hashRing.GetNode(key)
+		var node *hashring.Node
+		if current != nil {
+			node, err = current.GetNode(key)
+		} else {
+			node, err = c.hashRing.GetNode(key)
+		}
 		if err != nil {
 			return fmt.Errorf("error getting node for key %s: %w", key, err)
 		}
 
+		if bounded {
+			loadAware.AddLoad(node.ID, 1)
+		}
+
 		// Initialize the slice if it doesn't exist
 		if distribution[node.ID] == nil {
 			distribution[node.ID] = make([]string, 0)
@@ -44,8 +94,12 @@ func (c *Pantheon) Distribute(keys []string) error {
 		// Add the key to the node's distribution
 		distribution[node.ID] = append(distribution[node.ID], key)
 
+		// Nothing to write if the key stays where it is
+		if previous == node.ID {
+			continue
+		}
+
 		// Store the key-to-node mapping in Redis
-		keyMapKey := c.storage.makeKey("keymap", key)
 		if err := c.storage.redis.Set(c.ctx, keyMapKey, node.ID, 0).Err(); err != nil {
 			return fmt.Errorf("error storing key mapping: %w", err)
 		}
@@ -55,6 +109,18 @@ func (c *Pantheon) Distribute(keys []string) error {
 		if err := c.storage.redis.SAdd(c.ctx, nodeKeysKey, key).Err(); err != nil {
 			return fmt.Errorf("error storing node key: %w", err)
 		}
+
+		// Remove the key from its previous owner's set
+		if previous != "" {
+			previousKeysKey := c.storage.makeKey("nodekeys", previous)
+			if err := c.storage.redis.SRem(c.ctx, previousKeysKey, key).Err(); err != nil {
+				return fmt.Errorf("error removing node key: %w", err)
+			}
+		}
+	}
+
+	if current != nil {
+		c.distributed.Store(current)
 	}
 
 	// Log the distribution
@@ -66,13 +132,54 @@ func (c *Pantheon) Distribute(keys []string) error {
 	return nil
 }
 
+// snapshotRing is implemented by rings that publish immutable snapshots
+type snapshotRing interface {
+	Snapshot() *hashring.Snapshot
+}
+
+// distributionSnapshots returns the current ring snapshot, the snapshot used by
+// the last distribution and the hash ranges that moved in between
+// current is nil if the ring does not publish snapshots or bounded loads are
+// enabled, since bounded-load placement depends on loads as well as ranges.
+// previous is nil when no diff is available.
+func (c *Pantheon) distributionSnapshots(bounded bool) (current, previous *hashring.Snapshot, moved hashring.MovedRanges) {
+	ring, ok := c.hashRing.(snapshotRing)
+	if !ok {
+		return nil, nil, nil
+	}
+
+	if bounded {
+		// GetNode on a snapshot ignores loads, so let the ring place keys
+		return nil, nil, nil
+	}
+
+	current = ring.Snapshot()
+
+	previous = c.distributed.Load()
+	if previous == nil {
+		return current, nil, nil
+	}
+
+	moved, err := hashring.Diff(previous, current)
+	if err != nil {
+		return current, nil, nil
+	}
+
+	return current, previous, moved
+}
+
 // GetNodeKeys retrieves the keys assigned to a node.
 // This is used to determine which keys a node in the cluster is responsible for
+// Keys are not tracked in partition mode; use GetNodePartitions instead.
 func (c *Pantheon) GetNodeKeys(nodeID string) ([]string, error) {
 	if !c.started {
 		return nil, fmt.Errorf("cluster not started")
 	}
 
+	if c.partitioner != nil {
+		return nil, ErrPartitionMode
+	}
+
 	// Check if the node exists in the hash ring
 	exists := false
 	nodes := c.hashRing.GetNodes()
@@ -102,11 +209,27 @@ func (c *Pantheon) GetNodeKeys(nodeID string) ([]string, error) {
 }
 
 // GetKeyNode returns the node responsible for a specific key
+// With hot-key detection enabled every call counts as a lookup, and a hot key
+// resolves to a random node of its candidate set (see GetKeyCandidates).
 func (c *Pantheon) GetKeyNode(key string) (string, error) {
 	if !c.started {
 		return "", fmt.Errorf("cluster not started")
 	}
 
+	nodeID, err := c.getKeyNode(key)
+	if err != nil {
+		return "", err
+	}
+
+	return c.spreadHotKey(key, nodeID)
+}
+
+// getKeyNode returns the owner of a key, mapping it if needed
+func (c *Pantheon) getKeyNode(key string) (string, error) {
+	if c.partitioner != nil {
+		return c.GetPartitionNode(c.partitioner.Partition(key))
+	}
+
 	// First check if the key is already mapped in Redis
 	keyMapKey := c.storage.makeKey("keymap", key)
 	nodeID, err := c.storage.redis.Get(c.ctx, keyMapKey).Result()
@@ -136,5 +259,203 @@ func (c *Pantheon) GetKeyNode(key string) (string, error) {
 		return "", fmt.Errorf("error storing node key: %w", err)
 	}
 
+	if loadAware, bounded := c.boundedLoadRing(); bounded {
+		loadAware.AddLoad(node.ID, 1)
+	}
+
 	return node.ID, nil
 }
+
+// GetKeysNodes returns the node responsible for each of the given keys
+// It is the batch form of GetKeyNode. It is not named GetKeyNodes, which
+// already returns the replica set of a single key.
+// Existing mappings are fetched in one batched round trip, the remaining keys
+// are resolved against a single view of the hash ring and their mappings are
+// written back in one pipeline. In partition mode the keys resolve through
+// the partition table instead. Duplicate keys are resolved once, so a hot key
+// is counted and spread once per call, as in GetKeyNode.
+func (c *Pantheon) GetKeysNodes(keys []string) (map[string]string, error) {
+	if !c.started {
+		return nil, fmt.Errorf("cluster not started")
+	}
+
+	keys = uniqueKeys(keys)
+
+	result, err := c.getKeysNodes(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		nodeID, err := c.spreadHotKey(key, result[key])
+		if err != nil {
+			return nil, err
+		}
+		result[key] = nodeID
+	}
+
+	return result, nil
+}
+
+// uniqueKeys returns keys without duplicates, keeping the first occurrence of each
+func uniqueKeys(keys []string) []string {
+	seen := make(map[string]struct{}, len(keys))
+	unique := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		unique = append(unique, key)
+	}
+	return unique
+}
+
+// getKeysNodes returns the owner of each key, mapping keys as needed
+func (c *Pantheon) getKeysNodes(keys []string) (map[string]string, error) {
+	if c.partitioner != nil {
+		return c.getPartitionedKeysNodes(keys)
+	}
+
+	result := make(map[string]string, len(keys))
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	// First check which keys are already mapped in Redis
+	mapped, err := c.storage.GetKeyMappings(c.ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	unmapped := make([]string, 0)
+	for i, key := range keys {
+		if mapped[i] != "" {
+			result[key] = mapped[i]
+			continue
+		}
+		if _, pending := result[key]; !pending {
+			unmapped = append(unmapped, key)
+			result[key] = ""
+		}
+	}
+
+	if len(unmapped) == 0 {
+		return result, nil
+	}
+
+	// Otherwise, use the hash ring to determine the nodes
+	nodes, err := c.hashRing.GetNodesForKeys(unmapped)
+	if err != nil {
+		return nil, fmt.Errorf("error determining nodes for keys: %w", err)
+	}
+
+	assigned := make(map[string]string, len(unmapped))
+	for _, key := range unmapped {
+		assigned[key] = nodes[key].ID
+		result[key] = nodes[key].ID
+	}
+
+	// Store the mappings for future use
+	if err := c.storage.SetKeyMappings(c.ctx, assigned); err != nil {
+		return nil, err
+	}
+
+	if loadAware, bounded := c.boundedLoadRing(); bounded {
+		for _, nodeID := range assigned {
+			loadAware.AddLoad(nodeID, 1)
+		}
+	}
+
+	return result, nil
+}
+
+// GetKeyNodes returns the replica set for a key: rf distinct nodes in preference order
+// The first node is the primary and the rest are followers. The replica set is
+// persisted in Redis next to the key mapping and reused until one of its nodes
+// leaves or dies. Fewer than rf nodes are returned if fewer are available.
+// In partition mode the replica set of the key's partition is derived from the
+// ring on every call and not stored.
+func (c *Pantheon) GetKeyNodes(key string, rf int) ([]string, error) {
+	if !c.started {
+		return nil, fmt.Errorf("cluster not started")
+	}
+
+	if rf <= 0 {
+		return nil, fmt.Errorf("replication factor must be greater than 0")
+	}
+
+	if c.partitioner != nil {
+		return c.getPartitionReplicas(key, rf)
+	}
+
+	// First check if the replica set is already stored in Redis
+	stored, err := c.storage.GetKeyReplicas(c.ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stored) == rf && c.allNodesServing(stored) {
+		return stored, nil
+	}
+
+	// Otherwise, walk the hash ring for a fresh replica set
+	nodes, err := c.hashRing.GetNodesN(key, rf)
+	if err != nil {
+		return nil, fmt.Errorf("error determining replicas for key %s: %w", key, err)
+	}
+
+	replicas := make([]string, len(nodes))
+	for i, node := range nodes {
+		replicas[i] = node.ID
+	}
+
+	// Store the replica set for future use
+	if err := c.storage.SetKeyReplicas(c.ctx, key, stored, replicas); err != nil {
+		return nil, err
+	}
+
+	return replicas, nil
+}
+
+// allNodesServing reports whether every given node is in the hash ring and still serving keys
+// Draining nodes keep their existing replica sets until the keys are migrated.
+func (c *Pantheon) allNodesServing(nodeIDs []string) bool {
+	serving := make(map[string]bool)
+	for _, node := range c.hashRing.GetNodes() {
+		serving[node.ID] = node.IsServing()
+	}
+
+	for _, nodeID := range nodeIDs {
+		if !serving[nodeID] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// boundedLoadRing returns the hash ring as a LoadAware ring if bounded-load mode is enabled
+func (c *Pantheon) boundedLoadRing() (hashring.LoadAware, bool) {
+	loadAware, ok := c.hashRing.(hashring.LoadAware)
+	if !ok || !loadAware.BoundedLoad() {
+		return nil, false
+	}
+	return loadAware, true
+}
+
+// refreshRingLoads reports the size of each node's key set to the hash ring
+func (c *Pantheon) refreshRingLoads(ring hashring.LoadAware) error {
+	loads := make(map[string]int)
+	for _, node := range c.hashRing.GetNodes() {
+		nodeKeysKey := c.storage.makeKey("nodekeys", node.ID)
+		count, err := c.storage.redis.SCard(c.ctx, nodeKeysKey).Result()
+		if err != nil && err != redis.Nil {
+			return fmt.Errorf("error counting keys for node %s: %w", node.ID, err)
+		}
+		loads[node.ID] = int(count)
+	}
+
+	ring.SetLoads(loads)
+	return nil
+}
@@ -0,0 +1,43 @@
+package pantheon
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestGetKeysNodesDeduplicatesKeys(t *testing.T) {
+	server := miniredis.RunT(t)
+	p := newTestPantheon(t, server, func(o *Options) {
+		o.WithHotKeys(3, 2)
+	})
+	joinTestNodes(t, p, "node-a", "node-b")
+
+	owners, err := p.GetKeysNodes([]string{"user:1", "user:1", "user:1", "user:2", "user:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(owners) != 2 {
+		t.Fatalf("expected 2 owners, got %v", owners)
+	}
+
+	// Each distinct key counts as one lookup per call
+	if p.IsHotKey("user:1") {
+		t.Error("repeating a key within one call made it hot")
+	}
+
+	mapped, err := p.GetNodeKeys(owners["user:1"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for _, key := range mapped {
+		if key == "user:1" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("user:1 mapped %d times", count)
+	}
+}
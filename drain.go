@@ -0,0 +1,226 @@
+package pantheon
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Drain starts draining a node before it leaves the cluster
+// The node is marked as draining in Redis and in the hash ring, so new keys no
+// longer land on it while keys already mapped to it keep resolving there.
+// Its keys are then migrated to other nodes at the configured drain rate, and a
+// "drained" event is sent once the node has no keys left. Leave can then be
+// called safely.
+// In partition mode the node's partitions move as soon as it starts draining.
+func (c *Pantheon) Drain(id string) error {
+	if !c.started {
+		return fmt.Errorf("cluster not started")
+	}
+
+	node, err := c.storage.GetNode(c.ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if node == nil {
+		return fmt.Errorf("node %s not found", id)
+	}
+
+	if node.State == MemberDraining {
+		return nil
+	}
+
+	if node.State != MemberAlive {
+		return fmt.Errorf("node %s is %s and cannot be drained", id, node.State)
+	}
+
+	// Marks the node as draining in the hash ring and sends a draining event
+	if err := c.setNodeState(node, MemberDraining); err != nil {
+		return err
+	}
+
+	go c.migrateDrainingNode(id)
+
+	return nil
+}
+
+// Undrain cancels the drain of a node
+// A draining node becomes alive again and receives new keys; keys already
+// migrated stay where they are. A node that died or went into maintenance
+// while draining becomes alive instead of draining when it comes back.
+func (c *Pantheon) Undrain(id string) error {
+	if !c.started {
+		return fmt.Errorf("cluster not started")
+	}
+
+	node, err := c.storage.GetNode(c.ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if node == nil {
+		return fmt.Errorf("node %s not found", id)
+	}
+
+	if !node.DrainRequested {
+		return nil
+	}
+
+	if err := c.storage.ClearDrainRequest(c.ctx, id); err != nil {
+		return err
+	}
+	node.DrainRequested = false
+
+	if node.State != MemberDraining {
+		return nil
+	}
+
+	// Stops the migration and sends an undrained event
+	return c.setNodeState(node, MemberAlive)
+}
+
+// drainRetryMaxDelay is the longest wait between attempts after migration errors
+const drainRetryMaxDelay = time.Minute
+
+// drainMaxFailures is the number of consecutive failed attempts after which migration gives up
+const drainMaxFailures = 10
+
+// migrateDrainingNode moves the keys and replica sets of a draining node to
+// other nodes, drainRate keys per second, until none are left
+// Migration stops early if the node stops draining, e.g. because it left,
+// died or was undrained. Failed attempts are retried with exponential backoff; after
+// drainMaxFailures consecutive failures a "drain_failed" event is sent.
+func (c *Pantheon) migrateDrainingNode(id string) {
+	delay := time.Second
+	failures := 0
+
+	for {
+		done, err := c.migrateDrainingKeys(id)
+		if err != nil {
+			failures++
+			fmt.Printf("error migrating keys off draining node %s (attempt %d): %s\n", id, failures, err)
+
+			if failures >= drainMaxFailures {
+				if c.EventsCh != nil {
+					c.EventsCh <- PantheonEvent{
+						Event:  "drain_failed",
+						NodeID: id,
+						Error:  err,
+					}
+				}
+				return
+			}
+
+			delay = min(delay*2, drainRetryMaxDelay)
+		} else {
+			if done {
+				return
+			}
+			failures = 0
+			delay = time.Second
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-c.ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// migrateDrainingKeys moves up to drainRate keys and drainRate replica sets off a draining node
+// It reports true once the node is drained, sending a "drained" event, or is no
+// longer draining.
+func (c *Pantheon) migrateDrainingKeys(id string) (bool, error) {
+	node, err := c.storage.GetNode(c.ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	if node == nil || node.State != MemberDraining {
+		return true, nil
+	}
+
+	keys, err := c.storage.redis.SRandMemberN(c.ctx, c.storage.makeKey("nodekeys", id), int64(c.drainRate)).Result()
+	if err != nil {
+		return false, fmt.Errorf("error getting keys: %w", err)
+	}
+
+	replicated, err := c.storage.GetNodeReplicaKeys(c.ctx, id, c.drainRate)
+	if err != nil {
+		return false, err
+	}
+
+	if len(keys) == 0 && len(replicated) == 0 {
+		// Send a drained event
+		if c.EventsCh != nil {
+			c.EventsCh <- PantheonEvent{
+				Event:  "drained",
+				NodeID: id,
+			}
+		}
+
+		fmt.Printf("Node %s is drained\n", id)
+		return true, nil
+	}
+
+	if len(keys) > 0 {
+		if err := c.Distribute(keys); err != nil {
+			return false, err
+		}
+	}
+
+	for _, key := range replicated {
+		if err := c.migrateReplicaSet(key, id); err != nil {
+			return false, err
+		}
+	}
+
+	return false, nil
+}
+
+// migrateReplicaSet replaces the stored replica set of a key that includes a draining node
+func (c *Pantheon) migrateReplicaSet(key, nodeID string) error {
+	stored, err := c.storage.GetKeyReplicas(c.ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if !slices.Contains(stored, nodeID) {
+		// The replica set changed since it was indexed
+		return c.storage.SetKeyReplicas(c.ctx, key, []string{nodeID}, stored)
+	}
+
+	// The draining node is not available, so the ring picks a replacement
+	nodes, err := c.hashRing.GetNodesN(key, len(stored))
+	if err != nil {
+		return fmt.Errorf("error determining replicas for key %s: %w", key, err)
+	}
+
+	replicas := make([]string, len(nodes))
+	for i, node := range nodes {
+		replicas[i] = node.ID
+	}
+
+	return c.storage.SetKeyReplicas(c.ctx, key, stored, replicas)
+}
+
+// resumeDraining restarts the migration of every node that was draining when the process stopped
+func (c *Pantheon) resumeDraining() error {
+	members, err := c.storage.GetNodes(c.ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		if member.State == MemberDraining {
+			fmt.Printf("Resuming drain of node %s\n", member.ID)
+			go c.migrateDrainingNode(member.ID)
+		}
+	}
+
+	return nil
+}
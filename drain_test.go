@@ -0,0 +1,144 @@
+package pantheon
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// joinTestNodes joins push-checked nodes so no heartbeats are sent
+func joinTestNodes(t *testing.T, p *Pantheon, ids ...string) {
+	t.Helper()
+
+	for i, id := range ids {
+		op := &JoinOp{ID: id, Address: fmt.Sprintf("10.0.0.%d", i+1), Port: 80, HealthCheck: HealthCheckPush}
+		if err := p.Join(op); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// markDraining moves a node to the draining state without starting its migration
+func markDraining(t *testing.T, p *Pantheon, id string) {
+	t.Helper()
+
+	node, err := p.storage.GetNode(p.ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.setNodeState(node, MemberDraining); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// assertDrained fails if any key or replica set still refers to the node
+func assertDrained(t *testing.T, p *Pantheon, id string, keys []string) {
+	t.Helper()
+
+	for _, key := range keys {
+		replicas, err := p.storage.GetKeyReplicas(p.ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slices.Contains(replicas, id) {
+			t.Errorf("replica set of %s still includes %s: %v", key, id, replicas)
+		}
+
+		owner, err := p.GetKeyNode(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if owner == id {
+			t.Errorf("key %s is still owned by %s", key, id)
+		}
+	}
+}
+
+func TestDrainMigratesKeysAndReplicaSets(t *testing.T) {
+	server := miniredis.RunT(t)
+	p := newTestPantheon(t, server)
+	joinTestNodes(t, p, "node-a", "node-b", "node-c")
+
+	keys := make([]string, 50)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+		if _, err := p.GetKeyNodes(keys[i], 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Distribute(keys); err != nil {
+		t.Fatal(err)
+	}
+
+	indexed, err := p.storage.GetNodeReplicaKeys(p.ctx, "node-a", len(keys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(indexed) == 0 {
+		t.Fatal("node-a holds no replica sets")
+	}
+
+	markDraining(t, p, "node-a")
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 10 {
+			t.Fatal("node-a did not drain")
+		}
+
+		done, err := p.migrateDrainingKeys("node-a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done {
+			break
+		}
+	}
+
+	assertDrained(t, p, "node-a", keys)
+}
+
+func TestStartResumesDraining(t *testing.T) {
+	server := miniredis.RunT(t)
+	a := newTestPantheon(t, server)
+	joinTestNodes(t, a, "node-a", "node-b")
+
+	keys := make([]string, 20)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+		if _, err := a.GetKeyNodes(keys[i], 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Distribute(keys); err != nil {
+		t.Fatal(err)
+	}
+
+	// The process marking the node as draining stops before migrating anything
+	markDraining(t, a, "node-a")
+
+	b := newTestPantheon(t, server)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		owned, err := b.GetNodeKeys("node-a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		replicated, err := b.storage.GetNodeReplicaKeys(b.ctx, "node-a", len(keys))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(owned) == 0 && len(replicated) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("drain was not resumed: %d keys and %d replica sets left", len(owned), len(replicated))
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	assertDrained(t, b, "node-a", keys)
+}
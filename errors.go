@@ -32,6 +32,58 @@ var ErrInvalidHTTPClient = errors.New("http client is required")
 
 var ErrInvalidHashRing = errors.New("hash ring is required")
 
+var ErrInvalidDrainRate = errors.New("drain rate must be greater than 0")
+
+var ErrInvalidBoundedLoadFactor = errors.New("bounded load factor must be greater than or equal to 1")
+
+var ErrInvalidPartitionCount = errors.New("partition count must be greater than or equal to 0")
+
+var ErrInvalidHotKeyThreshold = errors.New("hot key threshold must be greater than or equal to 0")
+
+var ErrInvalidHotKeySpread = errors.New("hot key spread must be greater than or equal to 0")
+
+var ErrInvalidHotKeyHalfLife = errors.New("hot key half-life must be greater than 0")
+
+var ErrInvalidHotKeyLimit = errors.New("hot key limit must be greater than 0")
+
+var ErrInvalidPhiThreshold = errors.New("phi thresholds must be greater than or equal to 0 and the dead threshold at least the suspect threshold")
+
+var ErrInvalidPhiWindowSize = errors.New("phi accrual window size must be greater than 0")
+
+var ErrInvalidPhiMinStdDev = errors.New("phi accrual minimum standard deviation must be greater than or equal to 0")
+
+var ErrInvalidLeaseTTL = errors.New("lease ttl must be greater than 0")
+
+var ErrInvalidHeartbeatPolicy = errors.New("heartbeat policy interval, timeout and max failures must not be negative")
+
+var ErrInvalidHeartbeatPolicyTimeout = errors.New("heartbeat policy timeout must be less than its interval")
+
+var ErrInvalidTransition = errors.New("invalid member state transition")
+
+var ErrInvalidSuspicionTimeout = errors.New("suspicion timeout must be greater than or equal to 0")
+
+var ErrInvalidReviveSuccesses = errors.New("revive successes must be greater than 0")
+
+var ErrInvalidDegradedLatency = errors.New("degraded latency must be greater than or equal to 0")
+
+var ErrInvalidStatsWindow = errors.New("stats windows must be greater than 0")
+
+var ErrInvalidIndirectProbes = errors.New("indirect probes must be greater than or equal to 0")
+
+var ErrInvalidHeartbeatHistorySize = errors.New("heartbeat history size must be greater than 0")
+
+// ErrUnknownHealthCheck is returned when a node selects a health checker that is not registered
+var ErrUnknownHealthCheck = errors.New("unknown health check")
+
+// ErrRingConflict is returned when the hash ring could not be saved because other processes kept saving newer rings
+var ErrRingConflict = errors.New("hash ring was changed concurrently by other processes")
+
+// ErrPartitionMode is returned by per-key APIs that are unavailable in fixed-partition mode
+var ErrPartitionMode = errors.New("not available in partition mode")
+
+// ErrPartitionsDisabled is returned by partition APIs when fixed-partition mode is not enabled
+var ErrPartitionsDisabled = errors.New("partition mode is not enabled")
+
 // ErrNodeNotFound is return when a node property is not found in the storage
 type ErrNodePropertyNotFound struct {
 	property string
@@ -6,7 +6,20 @@ type PantheonEvent struct {
 	// "joined" - when a node joins the cluster
 	// "left" - when a node leaves the cluster
 	// "died" - when a node is considered dead (no heartbeat received/timeout)
+	// "revived" - when a dead or suspect node responds to heartbeats again
+	// "draining" - when a node starts draining and stops receiving new keys, or
+	//   resumes draining after it was dead or in maintenance
+	// "drained" - when a draining node has no keys left and can safely leave
+	// "drain_failed" - when migrating the keys of a draining node keeps failing
+	// "undrained" - when the drain of a node is cancelled and it is alive again
+	// "maintenance" - when a node is taken out of service for planned work
+	// "maintenance_ended" - when a node in maintenance is put back into service
+	// "degraded" - when an alive node's p95 heartbeat latency reaches the degraded threshold;
+	//   the flag is cleared when the node dies
+	// "recovered" - when a degraded node's p95 heartbeat latency drops below the threshold
 	Event string
 	// NodeID; the identifier of the node
 	NodeID string
+	// Error; the error behind a failure event such as "drain_failed"
+	Error error
 }
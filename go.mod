@@ -2,11 +2,14 @@ module github.com/fleetcontrolsio/pantheon
 
 go 1.24.1
 
-require github.com/cenkalti/backoff/v4 v4.3.0
+require (
+	github.com/alicebob/miniredis/v2 v2.39.0
+	github.com/cenkalti/backoff/v4 v4.3.0
+	github.com/cespare/xxhash/v2 v2.2.0
+	github.com/redis/go-redis/v9 v9.7.3
+)
 
 require (
-	github.com/cespare/xxhash/v2 v2.2.0 // indirect
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
-	github.com/redis/go-redis/v9 v9.7.3 // indirect
-	github.com/sourcegraph/conc v0.3.0 // indirect
+	github.com/yuin/gopher-lua v1.1.1 // indirect
 )
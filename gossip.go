@@ -0,0 +1,190 @@
+package pantheon
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/fleetcontrolsio/pantheon/pkg/hashring"
+	"github.com/fleetcontrolsio/pantheon/pkg/swim"
+)
+
+// Metadata keys gossiped by members to describe their place in the hash ring
+const (
+	GossipMetaWeight = "weight"
+	GossipMetaRegion = "region"
+	GossipMetaZone   = "zone"
+	GossipMetaRack   = "rack"
+)
+
+// gossipEventBuffer is the capacity of Gossip.EventsCh
+const gossipEventBuffer = 256
+
+// Gossip keeps a hash ring in sync with a SWIM gossip cluster
+// It is a membership backend for clusters without Redis: every node runs a
+// swim.Memberlist, probes its peers itself and learns about joins, failures
+// and departures through gossip. Membership changes update the hash ring and
+// are reported on EventsCh with the same events as Pantheon.
+type Gossip struct {
+	// members; the local member of the gossip cluster
+	members *swim.Memberlist
+	// hashRing; the hash ring kept in sync with the members
+	hashRing hashring.Ring
+	// EventsCh; a channel to send cluster events
+	// When it is full the oldest event is dropped, so a slow reader never
+	// stalls the gossip protocol; the ring itself is always up to date.
+	EventsCh chan PantheonEvent
+	// done; closed once the member events have been drained
+	done chan struct{}
+}
+
+// NewGossip keeps the given ring in sync with a memberlist
+// A nil ring selects a default hashring.HashRing. Members that are already
+// known, including the local one, are added to the ring immediately. The
+// events channel is closed once the memberlist shuts down. It is buffered, and
+// the oldest events are dropped if it is not drained.
+func NewGossip(members *swim.Memberlist, ring hashring.Ring) (*Gossip, error) {
+	if ring == nil {
+		ring = hashring.NewHashRing(10)
+	}
+
+	g := &Gossip{
+		members:  members,
+		hashRing: ring,
+		EventsCh: make(chan PantheonEvent, gossipEventBuffer),
+		done:     make(chan struct{}),
+	}
+
+	for _, member := range members.Members() {
+		if member.IsActive() {
+			if err := g.addNode(member); err != nil && err != hashring.ErrNodeExists {
+				return nil, err
+			}
+		}
+	}
+
+	go g.run()
+
+	return g, nil
+}
+
+// HashRing returns the hash ring kept in sync with the members
+func (g *Gossip) HashRing() hashring.Ring {
+	return g.hashRing
+}
+
+// Members returns every known member of the gossip cluster
+func (g *Gossip) Members() []swim.Member {
+	return g.members.Members()
+}
+
+// GetKeyNode returns the node responsible for a specific key
+func (g *Gossip) GetKeyNode(key string) (string, error) {
+	node, err := g.hashRing.GetNode(key)
+	if err != nil {
+		return "", fmt.Errorf("error determining node for key %s: %w", key, err)
+	}
+	return node.ID, nil
+}
+
+// Done returns a channel that is closed once the memberlist has shut down
+// and every event has been handled
+func (g *Gossip) Done() <-chan struct{} {
+	return g.done
+}
+
+// run applies member events to the ring until the memberlist shuts down
+func (g *Gossip) run() {
+	defer close(g.done)
+	defer close(g.EventsCh)
+
+	for event := range g.members.Events() {
+		name, err := g.apply(event)
+		if err != nil {
+			fmt.Printf("error applying gossip event %s for %s: %s\n", event.Type, event.Member.ID, err)
+			continue
+		}
+
+		if name == "" {
+			continue
+		}
+
+		g.emit(PantheonEvent{
+			Event:  name,
+			NodeID: event.Member.ID,
+		})
+	}
+}
+
+// emit sends an event without blocking, dropping the oldest queued event when the channel is full
+func (g *Gossip) emit(event PantheonEvent) {
+	for {
+		select {
+		case g.EventsCh <- event:
+			return
+		default:
+		}
+
+		select {
+		case dropped := <-g.EventsCh:
+			fmt.Printf("gossip events channel full, dropping %s event for %s\n", dropped.Event, dropped.NodeID)
+		default:
+		}
+	}
+}
+
+// apply updates the ring for a member event and returns the Pantheon event to send, if any
+// Suspect members keep serving keys, as they do in Pantheon.
+func (g *Gossip) apply(event swim.Event) (string, error) {
+	id := event.Member.ID
+
+	switch event.Type {
+	case swim.EventJoin:
+		err := g.addNode(event.Member)
+		if err == hashring.ErrNodeExists {
+			err = g.hashRing.UpdateNodeStatus(id, hashring.NodeStatusActive)
+		}
+		return "joined", err
+
+	case swim.EventAlive:
+		if event.Previous != swim.StateDead {
+			return "", nil
+		}
+		return "revived", g.hashRing.UpdateNodeStatus(id, hashring.NodeStatusActive)
+
+	case swim.EventDead:
+		return "died", g.hashRing.UpdateNodeStatus(id, hashring.NodeStatusInactive)
+
+	case swim.EventLeave:
+		err := g.hashRing.RemoveNode(id)
+		if err == hashring.ErrNodeNotFound {
+			err = nil
+		}
+		return "left", err
+	}
+
+	return "", nil
+}
+
+// addNode adds a member to the ring, reading its weight and topology from its metadata
+func (g *Gossip) addNode(member swim.Member) error {
+	weight := 1
+	if raw, ok := member.Meta[GossipMetaWeight]; ok {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid weight for node %s: %w", member.ID, err)
+		}
+		weight = parsed
+	}
+
+	return g.hashRing.AddNode(&hashring.Node{
+		ID:      member.ID,
+		Address: member.Addr,
+		Status:  hashring.NodeStatusActive,
+		Weight:  weight,
+		Topology: hashring.Topology{
+			Region: member.Meta[GossipMetaRegion],
+			Zone:   member.Meta[GossipMetaZone],
+			Rack:   member.Meta[GossipMetaRack],
+		},
+	})
+}
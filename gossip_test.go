@@ -0,0 +1,23 @@
+package pantheon
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGossipEventsDropOldestWhenFull(t *testing.T) {
+	g := &Gossip{EventsCh: make(chan PantheonEvent, gossipEventBuffer)}
+
+	// Nobody reads the channel; emitting must not block
+	for i := 0; i < gossipEventBuffer+10; i++ {
+		g.emit(PantheonEvent{Event: "joined", NodeID: fmt.Sprintf("node-%d", i)})
+	}
+
+	if len(g.EventsCh) != gossipEventBuffer {
+		t.Fatalf("expected %d buffered events, got %d", gossipEventBuffer, len(g.EventsCh))
+	}
+
+	if first := <-g.EventsCh; first.NodeID != "node-10" {
+		t.Errorf("expected the oldest events to be dropped, first event is for %s", first.NodeID)
+	}
+}
@@ -0,0 +1,406 @@
+package pantheon
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Built-in health check names, selectable per node with JoinOp.HealthCheck
+const (
+	// HealthCheckHTTP makes a GET request to the node's address and path
+	HealthCheckHTTP = "http"
+	// HealthCheckTCP opens a TCP connection to the node's address
+	HealthCheckTCP = "tcp"
+	// HealthCheckGRPC calls the gRPC Health Checking Protocol on the node's address
+	HealthCheckGRPC = "grpc"
+)
+
+// HealthChecker checks whether a node is healthy
+type HealthChecker interface {
+	// Check returns nil if the node is healthy
+	// The context is cancelled when the heartbeat timeout expires.
+	Check(ctx context.Context, node *Member) error
+}
+
+// HealthCheckFunc adapts an ordinary function to the HealthChecker interface
+type HealthCheckFunc func(ctx context.Context, node *Member) error
+
+// Check calls f(ctx, node)
+func (f HealthCheckFunc) Check(ctx context.Context, node *Member) error {
+	return f(ctx, node)
+}
+
+// defaultHTTPCheckBodyLimit is the default number of response body bytes read by HTTPChecker
+const defaultHTTPCheckBodyLimit = 64 * 1024
+
+// httpCheckDrainLimit is the number of bytes past the body limit discarded so the connection can be reused
+// Connections with longer bodies are closed.
+const httpCheckDrainLimit = 256 * 1024
+
+// HTTPChecker checks a node with an HTTP request to its address and path
+// With the zero configuration the node is healthy if a GET request returns any
+// 2xx status. The response body is read up to the body limit and the rest,
+// up to 256 KiB more, is discarded before closing, so connections are reused.
+type HTTPChecker struct {
+	// Client; the client used for the requests, nil uses http.DefaultClient
+	Client *http.Client
+	// Method; the request method (defaults to GET)
+	Method string
+	// Headers; extra request headers, e.g. an Authorization token
+	Headers http.Header
+	// MinStatus, MaxStatus; the accepted range of status codes, inclusive
+	// (MinStatus defaults to 200, MaxStatus to the end of MinStatus's class,
+	// e.g. 299 for 200 and 399 for 300)
+	MinStatus int
+	MaxStatus int
+	// MaxBodySize; the number of response body bytes read (defaults to 64 KiB)
+	// Assertions fail on larger bodies.
+	MaxBodySize int64
+	// BodyContains; if set, the body must contain this substring
+	BodyContains string
+	// JSONPath; if set, the body must be JSON and the value at this dot-separated
+	// path (e.g. "status" or "checks.0.state") must equal JSONValue
+	JSONPath string
+	// JSONValue; the expected value at JSONPath, compared as text
+	JSONValue string
+}
+
+// Check implements the HealthChecker interface
+func (h *HTTPChecker) Check(ctx context.Context, node *Member) error {
+	method := h.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	url := fmt.Sprintf("%s/%s", node.Address, node.Path)
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+
+	for name, values := range h.Headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("hearbeat request to %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	limit := h.MaxBodySize
+	if limit <= 0 {
+		limit = defaultHTTPCheckBodyLimit
+	}
+
+	// Read one byte past the limit to detect oversized bodies
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return fmt.Errorf("hearbeat request to %s failed reading body: %w", url, err)
+	}
+
+	// Finish reading oversized bodies so the connection goes back to the pool
+	if int64(len(body)) > limit {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, httpCheckDrainLimit))
+	}
+
+	minStatus, maxStatus := h.MinStatus, h.MaxStatus
+	if minStatus == 0 {
+		minStatus = http.StatusOK
+	}
+	if maxStatus == 0 {
+		maxStatus = minStatus/100*100 + 99
+	}
+	if maxStatus < minStatus {
+		return fmt.Errorf("invalid status range %d-%d", minStatus, maxStatus)
+	}
+
+	if resp.StatusCode < minStatus || resp.StatusCode > maxStatus {
+		return fmt.Errorf("hearbeat request to %s failed with status code %d", url, resp.StatusCode)
+	}
+
+	if h.BodyContains == "" && h.JSONPath == "" {
+		return nil
+	}
+
+	if int64(len(body)) > limit {
+		return fmt.Errorf("hearbeat response from %s exceeds %d bytes", url, limit)
+	}
+
+	if h.BodyContains != "" && !bytes.Contains(body, []byte(h.BodyContains)) {
+		return fmt.Errorf("hearbeat response from %s does not contain %q", url, h.BodyContains)
+	}
+
+	if h.JSONPath != "" {
+		var document any
+		if err := json.Unmarshal(body, &document); err != nil {
+			return fmt.Errorf("hearbeat response from %s is not valid JSON: %w", url, err)
+		}
+
+		value, ok := lookupJSONPath(document, h.JSONPath)
+		if !ok {
+			return fmt.Errorf("hearbeat response from %s has no value at %s", url, h.JSONPath)
+		}
+
+		if text := jsonText(value); text != h.JSONValue {
+			return fmt.Errorf("hearbeat response from %s has %s=%s, expected %s", url, h.JSONPath, text, h.JSONValue)
+		}
+	}
+
+	return nil
+}
+
+// lookupJSONPath returns the value at a dot-separated path in a decoded JSON document
+// Path segments index objects by key and arrays by position; a leading "$." is ignored.
+func lookupJSONPath(document any, path string) (any, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return document, true
+	}
+
+	value := document
+	for _, segment := range strings.Split(path, ".") {
+		switch current := value.(type) {
+		case map[string]any:
+			next, ok := current[segment]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []any:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(current) {
+				return nil, false
+			}
+			value = current[index]
+		default:
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// jsonText formats a decoded JSON value for comparison: strings as is,
+// everything else in its JSON encoding
+func jsonText(value any) string {
+	if text, ok := value.(string); ok {
+		return text
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+// TCPChecker checks a node by opening a TCP connection to its address
+// The node is healthy if the connection is accepted.
+type TCPChecker struct{}
+
+// Check implements the HealthChecker interface
+func (TCPChecker) Check(ctx context.Context, node *Member) error {
+	addr := memberHost(node.Address)
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("tcp connection to %s failed: %w", addr, err)
+	}
+
+	return conn.Close()
+}
+
+// GRPCChecker checks a node with the gRPC Health Checking Protocol
+// (grpc.health.v1.Health/Check). The node is healthy if the service is SERVING.
+// Addresses without a scheme or with http:// use HTTP/2 without TLS; https://
+// addresses use TLS.
+type GRPCChecker struct {
+	// Service; the service to check, empty checks the server as a whole
+	Service string
+	// Client; an HTTP/2 capable client, nil uses a shared default client
+	Client *http.Client
+}
+
+// grpcServingStatus names the values of grpc.health.v1.HealthCheckResponse.ServingStatus
+var grpcServingStatus = map[uint64]string{
+	0: "UNKNOWN",
+	1: "SERVING",
+	2: "NOT_SERVING",
+	3: "SERVICE_UNKNOWN",
+}
+
+// grpcMaxResponseSize is the largest health check response body read
+const grpcMaxResponseSize = 64 * 1024
+
+// grpcHealthClient is the default client for gRPC health checks
+var grpcHealthClient = sync.OnceValue(func() *http.Client {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Client{Transport: &http.Transport{Protocols: protocols}}
+})
+
+// Check implements the HealthChecker interface
+func (g *GRPCChecker) Check(ctx context.Context, node *Member) error {
+	target := node.Address
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+	url := strings.TrimSuffix(target, "/") + "/grpc.health.v1.Health/Check"
+
+	// HealthCheckRequest{service: 1} in a length-prefixed gRPC message
+	message := make([]byte, 0, len(g.Service)+2)
+	if g.Service != "" {
+		message = append(message, 0x0a)
+		message = binary.AppendUvarint(message, uint64(len(g.Service)))
+		message = append(message, g.Service...)
+	}
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	frame = append(frame, message...)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(frame))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+
+	client := g.Client
+	if client == nil {
+		client = grpcHealthClient()
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("grpc health check of %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("grpc health check of %s failed with status code %d", url, resp.StatusCode)
+	}
+
+	// Trailers are only available once the body is read to EOF, so read it all
+	// and fail rather than stop early when it is larger than any health response
+	body, err := io.ReadAll(io.LimitReader(resp.Body, grpcMaxResponseSize+1))
+	if err != nil {
+		return fmt.Errorf("grpc health check of %s failed: %w", url, err)
+	}
+	if len(body) > grpcMaxResponseSize {
+		return fmt.Errorf("grpc health check of %s failed: response larger than %d bytes", url, grpcMaxResponseSize)
+	}
+
+	// Trailers-only responses carry the status in the headers
+	status := resp.Trailer.Get("Grpc-Status")
+	if status == "" {
+		status = resp.Header.Get("Grpc-Status")
+	}
+	if status != "0" {
+		message := resp.Trailer.Get("Grpc-Message")
+		if message == "" {
+			message = resp.Header.Get("Grpc-Message")
+		}
+		return fmt.Errorf("grpc health check of %s failed with grpc status %s: %s", url, status, message)
+	}
+
+	servingStatus, err := parseHealthCheckResponse(body)
+	if err != nil {
+		return fmt.Errorf("grpc health check of %s failed: %w", url, err)
+	}
+
+	if servingStatus != 1 {
+		name, ok := grpcServingStatus[servingStatus]
+		if !ok {
+			name = fmt.Sprintf("status %d", servingStatus)
+		}
+		return fmt.Errorf("grpc health check of %s reported %s", url, name)
+	}
+
+	return nil
+}
+
+// parseHealthCheckResponse decodes the serving status of a framed HealthCheckResponse
+func parseHealthCheckResponse(body []byte) (uint64, error) {
+	if len(body) < 5 {
+		return 0, fmt.Errorf("truncated response")
+	}
+
+	if body[0] != 0 {
+		return 0, fmt.Errorf("compressed responses are not supported")
+	}
+
+	length := binary.BigEndian.Uint32(body[1:5])
+	if uint64(length) > uint64(len(body)-5) {
+		return 0, fmt.Errorf("truncated response")
+	}
+	message := body[5 : 5+length]
+
+	// An absent status field is UNKNOWN
+	var status uint64
+	for len(message) > 0 {
+		tag, n := binary.Uvarint(message)
+		if n <= 0 {
+			return 0, fmt.Errorf("malformed response")
+		}
+		message = message[n:]
+
+		switch tag & 7 {
+		case 0: // varint
+			value, n := binary.Uvarint(message)
+			if n <= 0 {
+				return 0, fmt.Errorf("malformed response")
+			}
+			message = message[n:]
+			if tag>>3 == 1 {
+				status = value
+			}
+		case 2: // length-delimited
+			size, n := binary.Uvarint(message)
+			if n <= 0 || size > uint64(len(message)-n) {
+				return 0, fmt.Errorf("malformed response")
+			}
+			message = message[n+int(size):]
+		default:
+			return 0, fmt.Errorf("malformed response")
+		}
+	}
+
+	return status, nil
+}
+
+// memberHost returns the host and port of a member address, which may include a scheme
+func memberHost(address string) string {
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return address
+}
+
+// healthChecker returns the checker registered under the given name
+// An empty name selects the HTTP checker.
+func (c *Pantheon) healthChecker(name string) (HealthChecker, bool) {
+	if name == "" {
+		name = HealthCheckHTTP
+	}
+	checker, ok := c.healthCheckers[name]
+	return checker, ok
+}
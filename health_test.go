@@ -0,0 +1,237 @@
+package pantheon
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// grpcHealthServer serves grpc.health.v1.Health/Check over HTTP/2 without TLS
+// It follows the wire format of the reference health service: known services
+// answer with a framed HealthCheckResponse and a Grpc-Status trailer, unknown
+// services with a trailers-only NOT_FOUND response.
+func grpcHealthServer(t *testing.T, statuses map[string]uint64, padding int) *httptest.Server {
+	t.Helper()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/grpc.health.v1.Health/Check" || r.Header.Get("Content-Type") != "application/grpc" {
+			w.Header().Set("Content-Type", "application/grpc")
+			w.Header().Set("Grpc-Status", "12")
+			w.Header().Set("Grpc-Message", "unknown method")
+			return
+		}
+
+		frame, err := io.ReadAll(r.Body)
+		if err != nil || len(frame) < 5 {
+			t.Errorf("bad request frame: %v", err)
+			return
+		}
+
+		// HealthCheckRequest{service: 1}
+		service := ""
+		if message := frame[5:]; len(message) > 0 {
+			length, n := binary.Uvarint(message[1:])
+			service = string(message[1+n : 1+n+int(length)])
+		}
+
+		status, ok := statuses[service]
+		if !ok {
+			w.Header().Set("Content-Type", "application/grpc")
+			w.Header().Set("Grpc-Status", "5")
+			w.Header().Set("Grpc-Message", "unknown service")
+			return
+		}
+
+		// HealthCheckResponse{status: 1}, padded with an unknown bytes field
+		message := binary.AppendUvarint([]byte{0x08}, status)
+		if padding > 0 {
+			message = append(message, 0x7a)
+			message = binary.AppendUvarint(message, uint64(padding))
+			message = append(message, make([]byte, padding)...)
+		}
+		response := make([]byte, 5, 5+len(message))
+		binary.BigEndian.PutUint32(response[1:], uint32(len(message)))
+		response = append(response, message...)
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		w.WriteHeader(http.StatusOK)
+		w.Write(response)
+		w.(http.Flusher).Flush()
+
+		// The status only arrives after the message
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Grpc-Status", "0")
+	})
+
+	server := httptest.NewUnstartedServer(handler)
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestGRPCChecker(t *testing.T) {
+	server := grpcHealthServer(t, map[string]uint64{"": 1, "orders": 1, "billing": 2}, 0)
+
+	tests := []struct {
+		name    string
+		service string
+		wantErr string
+	}{
+		{name: "server serving", service: ""},
+		{name: "service serving", service: "orders"},
+		{name: "service not serving", service: "billing", wantErr: "NOT_SERVING"},
+		{name: "unknown service", service: "inventory", wantErr: "grpc status 5: unknown service"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := &GRPCChecker{Service: tt.service}
+			err := checker.Check(context.Background(), &Member{Address: server.URL})
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGRPCCheckerReadsTrailersAfterLargeResponse(t *testing.T) {
+	// Fits within the limit but spans many HTTP/2 data frames
+	server := grpcHealthServer(t, map[string]uint64{"": 1}, 60*1024)
+	if err := (&GRPCChecker{}).Check(context.Background(), &Member{Address: server.URL}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Larger than any health response
+	server = grpcHealthServer(t, map[string]uint64{"": 1}, 70*1024)
+	err := (&GRPCChecker{}).Check(context.Background(), &Member{Address: server.URL})
+	if err == nil || !strings.Contains(err.Error(), "response larger than") {
+		t.Fatalf("expected a response size error, got %v", err)
+	}
+}
+
+// httpHealthServer answers every request with the given status and body
+// It records the method and Authorization header of the last request and
+// counts the connections it accepts.
+type httpHealthServer struct {
+	*httptest.Server
+	method      atomic.Value
+	auth        atomic.Value
+	connections atomic.Int64
+}
+
+func newHTTPHealthServer(t *testing.T, status int, body string) *httpHealthServer {
+	t.Helper()
+
+	s := &httpHealthServer{}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.method.Store(r.Method)
+		s.auth.Store(r.Header.Get("Authorization"))
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	s.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			s.connections.Add(1)
+		}
+	}
+	s.Start()
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func TestHTTPChecker(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		checker HTTPChecker
+		wantErr string
+	}{
+		{name: "default 2xx", status: http.StatusNoContent},
+		{name: "default rejects 3xx", status: http.StatusFound, wantErr: "status code 302"},
+		{name: "min status defaults max to its class", status: http.StatusFound, checker: HTTPChecker{MinStatus: 300}},
+		{name: "min status class excludes 4xx", status: http.StatusNotFound, checker: HTTPChecker{MinStatus: 300}, wantErr: "status code 404"},
+		{name: "explicit range", status: http.StatusNotFound, checker: HTTPChecker{MinStatus: 200, MaxStatus: 404}},
+		{name: "inverted range", status: http.StatusOK, checker: HTTPChecker{MinStatus: 300, MaxStatus: 200}, wantErr: "invalid status range"},
+		{name: "body contains", status: http.StatusOK, body: "all good", checker: HTTPChecker{BodyContains: "good"}},
+		{name: "body missing substring", status: http.StatusOK, body: "all bad", checker: HTTPChecker{BodyContains: "good"}, wantErr: "does not contain"},
+		{name: "body over limit", status: http.StatusOK, body: strings.Repeat("x", 100), checker: HTTPChecker{MaxBodySize: 10, BodyContains: "x"}, wantErr: "exceeds 10 bytes"},
+		{name: "body over limit without assertions", status: http.StatusOK, body: strings.Repeat("x", 100), checker: HTTPChecker{MaxBodySize: 10}},
+		{name: "json value", status: http.StatusOK, body: `{"status":"ok"}`, checker: HTTPChecker{JSONPath: "status", JSONValue: "ok"}},
+		{name: "json dollar prefix", status: http.StatusOK, body: `{"status":"ok"}`, checker: HTTPChecker{JSONPath: "$.status", JSONValue: "ok"}},
+		{name: "json array index", status: http.StatusOK, body: `{"checks":[{"state":"up"},{"state":"down"}]}`, checker: HTTPChecker{JSONPath: "checks.1.state", JSONValue: "down"}},
+		{name: "json non-string value", status: http.StatusOK, body: `{"ready":true,"load":0.5}`, checker: HTTPChecker{JSONPath: "ready", JSONValue: "true"}},
+		{name: "json wrong value", status: http.StatusOK, body: `{"status":"degraded"}`, checker: HTTPChecker{JSONPath: "status", JSONValue: "ok"}, wantErr: "expected ok"},
+		{name: "json index out of range", status: http.StatusOK, body: `{"checks":[]}`, checker: HTTPChecker{JSONPath: "checks.0", JSONValue: "x"}, wantErr: "no value at checks.0"},
+		{name: "invalid json", status: http.StatusOK, body: `not json`, checker: HTTPChecker{JSONPath: "status", JSONValue: "ok"}, wantErr: "not valid JSON"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newHTTPHealthServer(t, tt.status, tt.body)
+			err := tt.checker.Check(context.Background(), &Member{Address: server.URL, Path: "health"})
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHTTPCheckerSendsMethodAndHeaders(t *testing.T) {
+	server := newHTTPHealthServer(t, http.StatusOK, "")
+	checker := &HTTPChecker{
+		Method:  http.MethodHead,
+		Headers: http.Header{"Authorization": []string{"Bearer secret"}},
+	}
+
+	if err := checker.Check(context.Background(), &Member{Address: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if method := server.method.Load(); method != http.MethodHead {
+		t.Errorf("expected a HEAD request, got %v", method)
+	}
+	if auth := server.auth.Load(); auth != "Bearer secret" {
+		t.Errorf("expected the Authorization header, got %v", auth)
+	}
+}
+
+func TestHTTPCheckerReusesConnectionsAfterLargeBodies(t *testing.T) {
+	server := newHTTPHealthServer(t, http.StatusOK, strings.Repeat("x", 200*1024))
+	checker := &HTTPChecker{Client: &http.Client{Transport: &http.Transport{}}, BodyContains: "x"}
+
+	for i := 0; i < 3; i++ {
+		if err := checker.Check(context.Background(), &Member{Address: server.URL}); err == nil {
+			t.Fatal("expected the body limit to be exceeded")
+		}
+	}
+
+	if connections := server.connections.Load(); connections != 1 {
+		t.Errorf("expected one reused connection, got %d", connections)
+	}
+}
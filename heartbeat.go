@@ -3,10 +3,7 @@ package pantheon
 import (
 	"context"
 	"fmt"
-	"net/http"
-
-	"github.com/fleetcontrolsio/pantheon/pkg/hashring"
-	"github.com/sourcegraph/conc/pool"
+	"time"
 )
 
 type HearbeatEvent struct {
@@ -15,68 +12,179 @@ type HearbeatEvent struct {
 	Event string
 	// Error; the error that occurred
 	Error error
+	// At; when the health check completed
+	At time.Time
+	// RTT; how long the health check took, zero for push-based heartbeats
+	RTT time.Duration
 }
 
-func (c *Pantheon) performHeartbeat(ctx context.Context) {
+// performHeartbeat checks every node whose heartbeat is due
+// Each node is checked on its own interval and with its own timeout. Checks run
+// in the background, so a slow node does not delay the checks of other nodes.
+// The ticker is reset to the shortest interval of any node.
+func (c *Pantheon) performHeartbeat() {
+	// Pick up ring changes saved by other processes
+	if err := c.refreshRing(); err != nil {
+		fmt.Printf("error refreshing hash ring: %s\n", err)
+	}
+
 	// get the nodes
-	nodes, err := c.storage.GetNodes(ctx)
+	nodes, err := c.storage.GetNodes(c.ctx)
 	if err != nil {
+		fmt.Printf("error getting nodes for heartbeat: %s\n", err)
 		return
 	}
 
-	pool := pool.New().WithMaxGoroutines(c.heartbeatConcurrency)
+	now := time.Now()
+	tick := c.heartbeatInterval
+	seen := make(map[string]bool, len(nodes))
 
 	for _, node := range nodes {
 		node := node // Create a local copy for the goroutine
-		pool.Go(func() {
+		policy := c.heartbeatPolicy(&node)
+		seen[node.ID] = true
+		tick = min(tick, policy.Interval)
+
+		// Nodes in maintenance are not checked
+		if node.State == MemberMaintenance {
+			continue
+		}
+
+		// Half a tick of slack keeps jitter from pushing a check back a whole tick
+		if due, ok := c.heartbeatDue[node.ID]; ok && now.Add(c.heartbeatTick/2).Before(due) {
+			continue
+		}
+
+		// Skip nodes whose previous check is still running
+		if !c.startHeartbeat(node.ID) {
+			continue
+		}
+		c.heartbeatDue[node.ID] = now.Add(policy.Interval)
+
+		go func() {
+			defer c.finishHeartbeat(node.ID)
+
+			select {
+			case c.heartbeatSem <- struct{}{}:
+				defer func() { <-c.heartbeatSem }()
+			case <-c.ctx.Done():
+				return
+			}
+
 			// Increment heartbeat count
-			if err := c.storage.IncrementHeartbeats(ctx, node.ID); err != nil {
+			if err := c.storage.IncrementHeartbeats(c.ctx, node.ID); err != nil {
 				fmt.Printf("error incrementing heartbeat count: %s\n", err)
 			}
 
+			ctx, cancel := context.WithTimeout(c.ctx, policy.Timeout)
+			defer cancel()
+
 			c.performHearbeatRequest(ctx, &node)
-		})
+		}()
 	}
 
-	pool.Wait()
+	// Forget the schedule of nodes that left
+	for id := range c.heartbeatDue {
+		if !seen[id] {
+			delete(c.heartbeatDue, id)
+		}
+	}
+
+	if tick != c.heartbeatTick {
+		c.heartbeatTick = tick
+		c.hearbeat.Reset(tick)
+	}
+}
+
+// startHeartbeat marks a node as being checked
+// It returns false if a check of the node is already in flight.
+func (c *Pantheon) startHeartbeat(nodeID string) bool {
+	c.heartbeatMu.Lock()
+	defer c.heartbeatMu.Unlock()
+
+	if c.heartbeatProbing[nodeID] {
+		return false
+	}
+	c.heartbeatProbing[nodeID] = true
+	return true
+}
+
+// finishHeartbeat marks the check of a node as done
+func (c *Pantheon) finishHeartbeat(nodeID string) {
+	c.heartbeatMu.Lock()
+	defer c.heartbeatMu.Unlock()
+
+	delete(c.heartbeatProbing, nodeID)
 }
 
+// sendHeartbeatEvent hands a heartbeat event to the event handler
+// The event is dropped once the cluster's context is done.
+func (c *Pantheon) sendHeartbeatEvent(event HearbeatEvent) {
+	select {
+	case c.heartbeatEventCh <- event:
+	case <-c.ctx.Done():
+	}
+}
+
+// performHearbeatRequest checks a node with its health checker and reports the result
 func (c *Pantheon) performHearbeatRequest(ctx context.Context, node *Member) {
-	url := fmt.Sprintf("%s/%s", node.Address, node.Path)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
+	checker, ok := c.healthChecker(node.HealthCheck)
+	if !ok {
 		// TODO: Use a logger
-		fmt.Printf("error creating request: %s\n", err)
+		fmt.Printf("unknown health check %q for node %s, skipping\n", node.HealthCheck, node.ID)
 		return
 	}
-	// Use the parent context so that the request is cancelled if the parent context is cancelled
-	resp, err := c.http.Do(req.WithContext(ctx))
+
+	// Use the parent context so that the check is cancelled if the parent context is cancelled
+	start := time.Now()
+	err := checker.Check(ctx, node)
+	end := time.Now()
+	rtt := end.Sub(start)
+	// A lease check only reads Redis, so it says nothing about the node's latency
+	if node.HealthCheck == HealthCheckPush {
+		rtt = 0
+	}
+
+	record := ProbeRecord{At: end, RTT: rtt, Success: err == nil}
 	if err != nil {
-		fmt.Printf("error making request: %s\n", err)
-		c.heartbeatEventCh <- HearbeatEvent{
-			NodeID: node.ID,
-			Event:  "failure",
-			Error:  fmt.Errorf("hearbeat request to %s failed: %s", url, err.Error()),
+		record.Error = err.Error()
+	}
+	c.recordProbe(node.ID, record)
+
+	// Before reporting a failure, ask other nodes whether they can reach the node
+	if err != nil && c.indirectProbes > 0 && node.HealthCheck != HealthCheckPush {
+		if indirect, ok := c.indirectProbe(node); ok {
+			fmt.Printf("health check for node %s failed, but %s reached it\n", node.ID, indirect.Via)
+			c.sendHeartbeatEvent(HearbeatEvent{
+				NodeID: node.ID,
+				Event:  "success",
+				Error:  nil,
+				At:     indirect.At,
+				RTT:    indirect.RTT,
+			})
+			return
 		}
-		return
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("unexpected status code: %d\n", resp.StatusCode)
-		c.heartbeatEventCh <- HearbeatEvent{
+	if err != nil {
+		fmt.Printf("health check for node %s failed: %s\n", node.ID, err)
+		c.sendHeartbeatEvent(HearbeatEvent{
 			NodeID: node.ID,
 			Event:  "failure",
-			Error:  fmt.Errorf("hearbeat request to %s failed with status code %d", url, resp.StatusCode),
-		}
-
+			Error:  err,
+			At:     end,
+			RTT:    rtt,
+		})
 		return
 	}
 
-	c.heartbeatEventCh <- HearbeatEvent{
+	c.sendHeartbeatEvent(HearbeatEvent{
 		NodeID: node.ID,
 		Event:  "success",
 		Error:  nil,
-	}
+		At:     end,
+		RTT:    rtt,
+	})
 }
 
 // handleHeartbeatEvent handles the heartbeat events
@@ -94,110 +202,60 @@ func (c *Pantheon) handleHeartbeatEvent(event HearbeatEvent) {
 		return
 	}
 
+	// Nodes in maintenance are not judged by their heartbeats
+	if node.State == MemberMaintenance {
+		return
+	}
+
 	if event.Event == "success" {
 		// Update the node's last heartbeat
 		if err := c.storage.UpdateNodeHeartbeat(c.ctx, event.NodeID); err != nil {
 			fmt.Printf("error updating heartbeat: %s\n", err)
 			return
 		}
+	}
 
-		// If the node was previously dead or suspect, mark it as alive
-		if node.State != MemberAlive {
-			if err := c.storage.UpdateNodeState(c.ctx, event.NodeID, MemberAlive); err != nil {
-				fmt.Printf("error updating node state: %s\n", err)
-				return
-			}
-
-			// Update the node status in the hash ring
-			if c.hashRing != nil {
-				err = c.hashRing.UpdateNodeStatus(event.NodeID, hashring.NodeStatusActive)
-				if err != nil && err != hashring.ErrNodeNotFound {
-					fmt.Printf("error updating node status in hash ring: %s\n", err)
-				}
-			}
+	// Let the failure detector decide what the result means for the node
+	detected, err := c.failureDetector.Observe(c.ctx, node, HeartbeatResult{
+		Success: event.Event == "success",
+		At:      event.At,
+		RTT:     event.RTT,
+	})
+	if err != nil {
+		fmt.Printf("error detecting failure of node %s: %s\n", event.NodeID, err)
+		return
+	}
 
-			// Send a node revived event
-			if c.EventsCh != nil {
-				c.EventsCh <- PantheonEvent{
-					Event:  "revived",
-					NodeID: event.NodeID,
-				}
-			}
+	// Dead nodes must answer several heartbeats in a row to be revived
+	var successes int64
+	if node.State == MemberDead {
+		if event.Event == "success" {
+			successes, err = c.storage.IncrementHeartbeatSuccesses(c.ctx, event.NodeID)
+		} else {
+			err = c.storage.ResetHeartbeatSuccesses(c.ctx, event.NodeID)
 		}
-	} else if event.Event == "failure" {
-		// Increment the failure count
-		if err := c.storage.IncrementHeartbeatFailures(c.ctx, event.NodeID); err != nil {
-			fmt.Printf("error incrementing failure count: %s\n", err)
-			return
-		}
-
-		// Check if the node has exceeded the maximum failure count
-		failures, err := getHeartbeatFailureCount(node.HeartbeatFailures)
 		if err != nil {
-			fmt.Printf("error parsing failure count: %s\n", err)
+			fmt.Printf("error updating success count of node %s: %s\n", event.NodeID, err)
 			return
 		}
+	}
 
-		if failures >= c.heartbeatMaxFailures {
-			// Mark the node as dead
-			if node.State != MemberDead {
-				if err := c.storage.UpdateNodeState(c.ctx, event.NodeID, MemberDead); err != nil {
-					fmt.Printf("error updating node state: %s\n", err)
-					return
-				}
-
-				// Update the node status in the hash ring
-				if c.hashRing != nil {
-					err = c.hashRing.UpdateNodeStatus(event.NodeID, hashring.NodeStatusInactive)
-					if err != nil && err != hashring.ErrNodeNotFound {
-						fmt.Printf("error updating node status in hash ring: %s\n", err)
-					}
-				}
-
-				// Send a node dead event
-				if c.EventsCh != nil {
-					c.EventsCh <- PantheonEvent{
-						Event:  "died",
-						NodeID: event.NodeID,
-					}
-				}
-
-				// Trigger rebalancing after a node is marked dead
-				go func() {
-					// Get all keys assigned to this node
-					nodeKeysKey := c.storage.makeKey("nodekeys", event.NodeID)
-					keys, err := c.storage.redis.SMembers(c.ctx, nodeKeysKey).Result()
-					if err != nil {
-						fmt.Printf("error getting keys for dead node: %s\n", err)
-						return
-					}
-
-					if len(keys) > 0 {
-						fmt.Printf("Redistributing %d keys from dead node %s\n", len(keys), event.NodeID)
-						if err := c.Distribute(keys); err != nil {
-							fmt.Printf("error redistributing keys: %s\n", err)
-						}
-					}
-				}()
-			}
-		} else if node.State == MemberAlive {
-			// Mark the node as suspect
-			if err := c.storage.UpdateNodeState(c.ctx, event.NodeID, MemberSuspect); err != nil {
-				fmt.Printf("error updating node state: %s\n", err)
-				return
-			}
-		}
+	state := c.stateMachine.Next(node, c.heartbeatPolicy(node), detected, int(successes), time.Now())
+	if err := c.setNodeState(node, state); err != nil {
+		fmt.Printf("error updating state of node %s: %s\n", event.NodeID, err)
+		return
 	}
-}
 
-// getHeartbeatFailureCount parses the heartbeat failure count from a string
-func getHeartbeatFailureCount(count string) (int, error) {
-	var value int
-	_, err := fmt.Sscanf(count, "%d", &value)
-	if err != nil {
-		return 0, err
+	// Slow but alive nodes are reported as degraded
+	if c.degradedLatency > 0 && event.Event == "success" && node.State == MemberAlive {
+		c.checkDegraded(node)
 	}
-	return value, nil
+}
+
+// GetMember returns the member with the given ID, nil if it is not in the cluster
+// It can serve as an Agent's MemberLookup.
+func (c *Pantheon) GetMember(id string) (*Member, error) {
+	return c.storage.GetNode(c.ctx, id)
 }
 
 // GetNodeHealth returns the health status of a node
@@ -232,7 +290,7 @@ func (c *Pantheon) PingNode(nodeID string) error {
 		return fmt.Errorf("node %s not found", nodeID)
 	}
 
-	ctx, cancel := context.WithTimeout(c.ctx, c.heartbeatTimeout)
+	ctx, cancel := context.WithTimeout(c.ctx, c.heartbeatPolicy(node).Timeout)
 	defer cancel()
 
 	c.performHearbeatRequest(ctx, node)
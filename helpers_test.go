@@ -0,0 +1,58 @@
+package pantheon
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newTestPantheon starts a Pantheon instance against an in-memory Redis server
+// Cluster events are drained in the background and the instance is stopped
+// when the test ends.
+func newTestPantheon(t *testing.T, server *miniredis.Miniredis, configure ...func(*Options)) *Pantheon {
+	t.Helper()
+
+	port, err := strconv.Atoi(server.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := NewOptions().
+		WithName("test").
+		WithRedisHost(server.Host()).
+		WithRedisPort(port).
+		WithHTTPClient(&http.Client{Timeout: time.Second}).
+		WithHeartbeatInterval(time.Hour).
+		WithHeartbeatTimeout(time.Second)
+	for _, fn := range configure {
+		fn(options)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	p, err := New(ctx, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			select {
+			case <-p.EventsCh:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
@@ -0,0 +1,32 @@
+package pantheon
+
+import (
+	"fmt"
+	"time"
+)
+
+// ProbeRecord is one entry of a node's heartbeat history
+type ProbeRecord struct {
+	// At; when the probe completed
+	At time.Time `json:"at"`
+	// RTT; how long the probe took, zero for push-based heartbeats
+	RTT time.Duration `json:"rtt"`
+	// Success; whether the node passed the probe
+	Success bool `json:"success"`
+	// Via; the node that probed indirectly, empty for direct probes
+	Via string `json:"via,omitempty"`
+	// Error; why the probe failed
+	Error string `json:"error,omitempty"`
+}
+
+// GetNodeHistory returns the most recent probes of a node, oldest first
+func (c *Pantheon) GetNodeHistory(nodeID string) ([]ProbeRecord, error) {
+	return c.storage.GetProbeHistory(c.ctx, nodeID)
+}
+
+// recordProbe appends a probe to a node's heartbeat history, logging failures
+func (c *Pantheon) recordProbe(nodeID string, record ProbeRecord) {
+	if err := c.storage.AddProbeRecord(c.ctx, nodeID, record, c.heartbeatHistorySize); err != nil {
+		fmt.Printf("error recording probe of node %s: %s\n", nodeID, err)
+	}
+}
@@ -0,0 +1,308 @@
+package pantheon
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/fleetcontrolsio/pantheon/pkg/hashring"
+)
+
+const (
+	// hotKeySketchDepth is the number of rows in the count-min sketch
+	hotKeySketchDepth = 4
+	// hotKeySketchWidth is the number of counters per row
+	hotKeySketchWidth = 4096
+)
+
+// HotKey is a key whose lookup rate is above the hot-key threshold
+type HotKey struct {
+	// Key; the hot key
+	Key string
+	// Lookups; the estimated number of recent lookups, decayed over time
+	Lookups int
+}
+
+// hotKeyTracker counts key lookups with a count-min sketch whose counters are
+// halved every half-life, so the counts follow the recent lookup rate
+// Memory use is fixed however many distinct keys are looked up: the sketch has
+// a fixed size and at most limit hot keys are listed, the coldest being evicted.
+type hotKeyTracker struct {
+	mu        sync.Mutex
+	counters  [hotKeySketchDepth][hotKeySketchWidth]uint32
+	threshold uint32
+	halfLife  time.Duration
+	lastDecay time.Time
+	// limit; the maximum number of hot keys listed
+	limit int
+	// hot; the listed keys above the threshold by key
+	hot map[string]*hotKeyEntry
+	// coldest; the listed keys as a min-heap on their estimated counts
+	coldest hotKeyHeap
+}
+
+// hotKeyEntry is a listed hot key and its estimated count
+type hotKeyEntry struct {
+	key     string
+	lookups uint32
+	index   int // Position in the heap
+}
+
+// hotKeyHeap is a min-heap of hot keys ordered by estimated count
+type hotKeyHeap []*hotKeyEntry
+
+func (h hotKeyHeap) Len() int           { return len(h) }
+func (h hotKeyHeap) Less(i, j int) bool { return h[i].lookups < h[j].lookups }
+func (h hotKeyHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *hotKeyHeap) Push(x any) {
+	entry := x.(*hotKeyEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *hotKeyHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
+}
+
+// newHotKeyTracker creates a tracker flagging keys with at least threshold decayed lookups
+// At most limit hot keys are listed.
+func newHotKeyTracker(threshold, limit int, halfLife time.Duration) *hotKeyTracker {
+	return &hotKeyTracker{
+		threshold: uint32(threshold),
+		halfLife:  halfLife,
+		lastDecay: time.Now(),
+		limit:     limit,
+		hot:       make(map[string]*hotKeyEntry),
+	}
+}
+
+// observe records a lookup of key and reports whether the key is hot
+func (t *hotKeyTracker) observe(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.decay(time.Now())
+
+	// Conservative update: only raise the counters holding the current estimate
+	indexes := sketchIndexes(key)
+	estimate := t.estimate(indexes)
+	for row, index := range indexes {
+		if t.counters[row][index] == estimate {
+			t.counters[row][index]++
+		}
+	}
+	estimate++
+
+	if estimate < t.threshold {
+		return false
+	}
+
+	t.list(key, estimate)
+
+	return true
+}
+
+// list records the estimated count of a hot key, evicting the coldest key when full
+// A key colder than every listed key is not listed, but is still hot.
+// The caller must hold the lock.
+func (t *hotKeyTracker) list(key string, estimate uint32) {
+	if entry, ok := t.hot[key]; ok {
+		entry.lookups = estimate
+		heap.Fix(&t.coldest, entry.index)
+		return
+	}
+
+	if len(t.coldest) >= t.limit {
+		if t.coldest[0].lookups >= estimate {
+			return
+		}
+		evicted := heap.Pop(&t.coldest).(*hotKeyEntry)
+		delete(t.hot, evicted.key)
+	}
+
+	entry := &hotKeyEntry{key: key, lookups: estimate}
+	heap.Push(&t.coldest, entry)
+	t.hot[key] = entry
+}
+
+// isHot reports whether key is hot without recording a lookup
+func (t *hotKeyTracker) isHot(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.decay(time.Now())
+
+	return t.estimate(sketchIndexes(key)) >= t.threshold
+}
+
+// hotKeys returns the hot keys, hottest first
+func (t *hotKeyTracker) hotKeys() []HotKey {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.decay(time.Now())
+
+	keys := make([]HotKey, 0, len(t.hot))
+	for key, entry := range t.hot {
+		keys = append(keys, HotKey{Key: key, Lookups: int(entry.lookups)})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Lookups != keys[j].Lookups {
+			return keys[i].Lookups > keys[j].Lookups
+		}
+		return keys[i].Key < keys[j].Key
+	})
+
+	return keys
+}
+
+// decay halves every counter once per elapsed half-life and drops keys that cooled down
+// The caller must hold the lock.
+func (t *hotKeyTracker) decay(now time.Time) {
+	periods := int(now.Sub(t.lastDecay) / t.halfLife)
+	if periods <= 0 {
+		return
+	}
+	t.lastDecay = t.lastDecay.Add(time.Duration(periods) * t.halfLife)
+
+	shift := min(periods, 32)
+	for row := range t.counters {
+		for i := range t.counters[row] {
+			t.counters[row][i] >>= shift
+		}
+	}
+
+	listed := t.coldest[:0]
+	for _, entry := range t.coldest {
+		entry.lookups = t.estimate(sketchIndexes(entry.key))
+		if entry.lookups < t.threshold {
+			delete(t.hot, entry.key)
+			continue
+		}
+		listed = append(listed, entry)
+	}
+	clear(t.coldest[len(listed):])
+	t.coldest = listed
+	heap.Init(&t.coldest)
+}
+
+// estimate returns the smallest counter for the given indexes
+// The caller must hold the lock.
+func (t *hotKeyTracker) estimate(indexes [hotKeySketchDepth]int) uint32 {
+	estimate := t.counters[0][indexes[0]]
+	for row := 1; row < hotKeySketchDepth; row++ {
+		estimate = min(estimate, t.counters[row][indexes[row]])
+	}
+	return estimate
+}
+
+// sketchIndexes returns the counter index of key in each row of the sketch
+// The row hashes are derived from one 64-bit hash by double hashing.
+func sketchIndexes(key string) [hotKeySketchDepth]int {
+	hash := xxhash.Sum64String(key)
+	h1 := uint32(hash)
+	h2 := uint32(hash>>32) | 1
+
+	var indexes [hotKeySketchDepth]int
+	for row := range indexes {
+		indexes[row] = int((h1 + uint32(row)*h2) % hotKeySketchWidth)
+	}
+	return indexes
+}
+
+// IsHotKey reports whether a key is currently hot
+// It always returns false when hot-key detection is disabled.
+func (c *Pantheon) IsHotKey(key string) bool {
+	if c.hotKeys == nil {
+		return false
+	}
+	return c.hotKeys.isHot(key)
+}
+
+// HotKeys returns the keys that are currently hot, hottest first
+// At most the hot key limit is returned (see WithHotKeyLimit).
+func (c *Pantheon) HotKeys() []HotKey {
+	if c.hotKeys == nil {
+		return []HotKey{}
+	}
+	return c.hotKeys.hotKeys()
+}
+
+// GetKeyCandidates returns every node that may serve a key
+// For a key that is not hot this is just its owner. A hot key is spread over
+// its owner and the next ring successors, up to the configured spread.
+func (c *Pantheon) GetKeyCandidates(key string) ([]string, error) {
+	if !c.started {
+		return nil, fmt.Errorf("cluster not started")
+	}
+
+	owner, err := c.getKeyNode(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.IsHotKey(key) {
+		return []string{owner}, nil
+	}
+
+	return c.keyCandidates(key, owner)
+}
+
+// keyCandidates returns the owner of a hot key followed by its next ring successors
+func (c *Pantheon) keyCandidates(key, owner string) ([]string, error) {
+	if c.hotKeySpread <= 1 {
+		return []string{owner}, nil
+	}
+
+	// Successors follow the key's partition in partition mode
+	ringKey := key
+	if c.partitioner != nil {
+		ringKey = hashring.PartitionKey(c.partitioner.Partition(key))
+	}
+
+	nodes, err := c.hashRing.GetNodesN(ringKey, c.hotKeySpread)
+	if err != nil {
+		return nil, fmt.Errorf("error determining candidates for key %s: %w", key, err)
+	}
+
+	candidates := make([]string, 0, c.hotKeySpread)
+	candidates = append(candidates, owner)
+	for _, node := range nodes {
+		if len(candidates) == c.hotKeySpread {
+			break
+		}
+		if node.ID != owner {
+			candidates = append(candidates, node.ID)
+		}
+	}
+
+	return candidates, nil
+}
+
+// spreadHotKey records a lookup of key and, if the key is hot, returns a random
+// candidate instead of its owner
+func (c *Pantheon) spreadHotKey(key, owner string) (string, error) {
+	if c.hotKeys == nil || !c.hotKeys.observe(key) || c.hotKeySpread <= 1 {
+		return owner, nil
+	}
+
+	candidates, err := c.keyCandidates(key, owner)
+	if err != nil {
+		return "", err
+	}
+
+	return candidates[rand.IntN(len(candidates))], nil
+}
@@ -0,0 +1,56 @@
+package pantheon
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestHotKeyTrackerKeepsHottestKeys(t *testing.T) {
+	tracker := newHotKeyTracker(2, 3, time.Hour)
+
+	// key-i is looked up i+2 times, so every key is hot and key-99 is the hottest
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		for n := 0; n < i+2; n++ {
+			tracker.observe(key)
+		}
+	}
+
+	if len(tracker.hot) != 3 || len(tracker.coldest) != 3 {
+		t.Fatalf("tracker lists %d keys, heap holds %d; want 3", len(tracker.hot), len(tracker.coldest))
+	}
+
+	hot := tracker.hotKeys()
+	for i, want := range []string{"key-99", "key-98", "key-97"} {
+		if hot[i].Key != want {
+			t.Errorf("hot key %d is %s, want %s", i, hot[i].Key, want)
+		}
+	}
+
+	// Keys dropped from the list are still hot
+	if !tracker.isHot("key-0") {
+		t.Error("evicted key is no longer reported as hot")
+	}
+}
+
+func TestHotKeyTrackerDecayDropsColdKeys(t *testing.T) {
+	tracker := newHotKeyTracker(4, 10, time.Minute)
+
+	for n := 0; n < 4; n++ {
+		tracker.observe("warm")
+	}
+	for n := 0; n < 16; n++ {
+		tracker.observe("hot")
+	}
+
+	// One half-life later warm drops to 2 lookups and hot to 8
+	tracker.decay(tracker.lastDecay.Add(time.Minute))
+
+	if _, ok := tracker.hot["warm"]; ok {
+		t.Error("warm key is still listed after cooling down")
+	}
+	if len(tracker.coldest) != 1 || tracker.coldest[0].key != "hot" || tracker.coldest[0].lookups != 8 {
+		t.Errorf("unexpected heap after decay: %+v", tracker.coldest)
+	}
+}
@@ -0,0 +1,137 @@
+package pantheon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// indirectProbe asks up to k other alive nodes to probe a node that failed a
+// direct health check
+// It returns the first successful probe, or false if every probe failed or no
+// other node could be asked. Every attempt is recorded in the node's history.
+func (c *Pantheon) indirectProbe(node *Member) (ProbeRecord, bool) {
+	relays, err := c.indirectProbeRelays(node.ID)
+	if err != nil {
+		fmt.Printf("error selecting indirect probes for node %s: %s\n", node.ID, err)
+		return ProbeRecord{}, false
+	}
+
+	if len(relays) == 0 {
+		return ProbeRecord{}, false
+	}
+
+	// The direct check may have used up the heartbeat deadline
+	ctx, cancel := context.WithTimeout(c.ctx, c.heartbeatPolicy(node).Timeout)
+	defer cancel()
+
+	records := make([]ProbeRecord, len(relays))
+	var wg sync.WaitGroup
+	for i := range relays {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			records[i] = c.requestProbe(ctx, &relays[i], node)
+		}(i)
+	}
+	wg.Wait()
+
+	var success ProbeRecord
+	ok := false
+	for _, record := range records {
+		c.recordProbe(node.ID, record)
+		if record.Success && !ok {
+			success, ok = record, true
+		}
+	}
+
+	return success, ok
+}
+
+// indirectProbeRelays returns up to k random alive nodes that can probe the given node
+// Nodes using push-based heartbeats run no reachable agent and are skipped.
+func (c *Pantheon) indirectProbeRelays(nodeID string) ([]Member, error) {
+	members, err := c.storage.GetNodes(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	relays := make([]Member, 0, len(members))
+	for _, member := range members {
+		if member.ID != nodeID && member.State == MemberAlive && member.HealthCheck != HealthCheckPush {
+			relays = append(relays, member)
+		}
+	}
+
+	rand.Shuffle(len(relays), func(i, j int) {
+		relays[i], relays[j] = relays[j], relays[i]
+	})
+
+	if len(relays) > c.indirectProbes {
+		relays = relays[:c.indirectProbes]
+	}
+
+	return relays, nil
+}
+
+// requestProbe asks the agent of relay to probe target
+func (c *Pantheon) requestProbe(ctx context.Context, relay, target *Member) ProbeRecord {
+	record := ProbeRecord{Via: relay.ID}
+	start := time.Now()
+
+	fail := func(err error) ProbeRecord {
+		record.At = time.Now()
+		record.RTT = record.At.Sub(start)
+		record.Error = err.Error()
+		return record
+	}
+
+	body, err := json.Marshal(probeRequest{
+		ID:          target.ID,
+		Address:     target.Address,
+		Path:        target.Path,
+		HealthCheck: target.HealthCheck,
+	})
+	if err != nil {
+		return fail(err)
+	}
+
+	url := fmt.Sprintf("%s/%s", relay.Address, strings.TrimPrefix(c.indirectProbePath, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fail(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.indirectProbeToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.indirectProbeToken)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fail(fmt.Errorf("indirect probe request to %s failed: %w", url, err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+		return fail(fmt.Errorf("indirect probe request to %s failed with status code %d", url, resp.StatusCode))
+	}
+
+	var result probeResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&result); err != nil {
+		return fail(fmt.Errorf("invalid indirect probe response from %s: %w", url, err))
+	}
+
+	record.At = time.Now()
+	record.RTT = time.Duration(result.RTT) * time.Microsecond
+	record.Success = result.Success
+	record.Error = result.Error
+	return record
+}
@@ -0,0 +1,125 @@
+package pantheon
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// HealthCheckPush selects push-based heartbeats for a node
+// Instead of being probed, the node refreshes a lease in Redis with
+// Pantheon.Heartbeat or a HeartbeatClient. Every heartbeat interval the lease
+// is checked, and an expired lease counts as a failed heartbeat.
+const HealthCheckPush = "push"
+
+// leaseChecker checks that a node's lease has not expired
+type leaseChecker struct {
+	storage *Storage
+}
+
+// Check implements the HealthChecker interface
+func (l *leaseChecker) Check(ctx context.Context, node *Member) error {
+	ok, err := l.storage.HasLease(ctx, node.ID)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf("lease of node %s expired", node.ID)
+	}
+
+	return nil
+}
+
+// Heartbeat refreshes the lease of a node using push-based heartbeats
+// Nodes should call it several times per lease TTL.
+func (c *Pantheon) Heartbeat(nodeID string) error {
+	if !c.started {
+		return fmt.Errorf("cluster not started")
+	}
+
+	return renewLease(c.ctx, c.storage, nodeID, c.leaseTTL)
+}
+
+// HeartbeatClient refreshes the lease of a single node without running a cluster
+// It is meant to be embedded in the nodes themselves. It only needs access to
+// Redis and the name and prefix of the cluster.
+type HeartbeatClient struct {
+	storage *Storage
+	nodeID  string
+	ttl     time.Duration
+}
+
+// NewHeartbeatClient creates a heartbeat client for a node
+// The options must name the same cluster and Redis server as the Pantheon
+// instances monitoring it.
+func NewHeartbeatClient(ctx context.Context, options *Options, nodeID string) (*HeartbeatClient, error) {
+	// Only the cluster identity and Redis settings matter to the client
+	if options.prefix == "" {
+		return nil, ErrInvalidPrefix
+	}
+
+	if options.name == "" {
+		return nil, ErrInvalidName
+	}
+
+	if options.leaseTTL <= 0 {
+		return nil, ErrInvalidLeaseTTL
+	}
+
+	redisClient, err := NewRedisClient(ctx, &RedisClientOptions{
+		Host:              options.redisHost,
+		Port:              options.redisPort,
+		Password:          options.redisPassword,
+		DB:                options.redisDB,
+		MaxRetries:        options.redisMaxRetries,
+		RetryBackOffLimit: options.redisRetryBackoff,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &HeartbeatClient{
+		storage: NewStorage(options.prefix, options.name, redisClient),
+		nodeID:  nodeID,
+		ttl:     options.leaseTTL,
+	}, nil
+}
+
+// Heartbeat refreshes the node's lease once
+func (h *HeartbeatClient) Heartbeat(ctx context.Context) error {
+	return renewLease(ctx, h.storage, h.nodeID, h.ttl)
+}
+
+// Run refreshes the node's lease every interval until the context is cancelled
+// Failed refreshes are logged and retried at the next interval.
+func (h *HeartbeatClient) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := h.Heartbeat(ctx); err != nil {
+			fmt.Printf("error refreshing lease of node %s: %s\n", h.nodeID, err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// renewLease refreshes the lease of a node that is a member of the cluster
+func renewLease(ctx context.Context, storage *Storage, nodeID string, ttl time.Duration) error {
+	node, err := storage.GetNode(ctx, nodeID)
+	if err != nil {
+		return err
+	}
+
+	if node == nil {
+		return fmt.Errorf("node %s not found", nodeID)
+	}
+
+	return storage.RenewLease(ctx, nodeID, ttl)
+}
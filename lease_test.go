@@ -0,0 +1,163 @@
+package pantheon
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestLeaseExpiry(t *testing.T) {
+	server := miniredis.RunT(t)
+	p := newTestPantheon(t, server, func(o *Options) {
+		o.WithLeaseTTL(10 * time.Second)
+	})
+	joinTestNodes(t, p, "node-a")
+
+	node, err := p.GetMember("node-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker := &leaseChecker{storage: p.storage}
+
+	// Join hands out the first lease
+	if err := checker.Check(p.ctx, node); err != nil {
+		t.Fatalf("expected a lease after join, got %s", err)
+	}
+
+	server.FastForward(11 * time.Second)
+	if err := checker.Check(p.ctx, node); err == nil {
+		t.Fatal("expected the lease to expire")
+	}
+}
+
+func TestLeaseRenewal(t *testing.T) {
+	server := miniredis.RunT(t)
+	p := newTestPantheon(t, server, func(o *Options) {
+		o.WithLeaseTTL(10 * time.Second)
+	})
+	joinTestNodes(t, p, "node-a")
+
+	node, err := p.GetMember("node-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker := &leaseChecker{storage: p.storage}
+
+	for i := 0; i < 3; i++ {
+		server.FastForward(6 * time.Second)
+		if err := p.Heartbeat("node-a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Renewed every 6s, the lease outlives its first 10s TTL
+	if err := checker.Check(p.ctx, node); err != nil {
+		t.Fatalf("expected a renewed lease, got %s", err)
+	}
+
+	server.FastForward(11 * time.Second)
+	if err := checker.Check(p.ctx, node); err == nil {
+		t.Fatal("expected the lease to expire without renewals")
+	}
+}
+
+func TestHeartbeatClient(t *testing.T) {
+	server := miniredis.RunT(t)
+	p := newTestPantheon(t, server, func(o *Options) {
+		o.WithLeaseTTL(10 * time.Second)
+	})
+	joinTestNodes(t, p, "node-a")
+
+	port, err := strconv.Atoi(server.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := NewOptions().
+		WithName("test").
+		WithRedisHost(server.Host()).
+		WithRedisPort(port).
+		WithLeaseTTL(10 * time.Second)
+
+	client, err := NewHeartbeatClient(context.Background(), options, "node-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.FastForward(6 * time.Second)
+	if err := client.Heartbeat(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	server.FastForward(6 * time.Second)
+
+	node, err := p.GetMember("node-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (&leaseChecker{storage: p.storage}).Check(p.ctx, node); err != nil {
+		t.Fatalf("expected the client to renew the lease, got %s", err)
+	}
+}
+
+func TestHeartbeatRejectsUnknownNodes(t *testing.T) {
+	server := miniredis.RunT(t)
+	p := newTestPantheon(t, server)
+
+	if err := p.Heartbeat("missing"); err == nil {
+		t.Fatal("expected an error for an unknown node")
+	}
+
+	// No lease is handed out to a node that is not a member
+	ok, err := p.storage.HasLease(p.ctx, "missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("unknown node was given a lease")
+	}
+}
+
+func TestPushHeartbeatsRecordNoLatency(t *testing.T) {
+	server := miniredis.RunT(t)
+	p := newTestPantheon(t, server, func(o *Options) {
+		o.WithDegradedLatency(time.Nanosecond)
+	})
+	joinTestNodes(t, p, "node-a")
+
+	node, err := p.GetMember("node-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < degradedMinProbes; i++ {
+		p.performHearbeatRequest(p.ctx, node)
+	}
+
+	history, err := p.GetNodeHistory("node-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) < degradedMinProbes {
+		t.Fatalf("expected %d probes, got %d", degradedMinProbes, len(history))
+	}
+	for _, record := range history {
+		if !record.Success || record.RTT != 0 {
+			t.Fatalf("expected a successful probe without RTT, got %+v", record)
+		}
+	}
+
+	stats, err := p.GetNodeStats("node-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.P50 != 0 || stats.P99 != 0 {
+		t.Errorf("expected no latency percentiles, got p50=%s p99=%s", stats.P50, stats.P99)
+	}
+
+	// Any latency reaches the threshold, but there is none to judge
+	p.checkDegraded(node)
+	if node, _ := p.GetMember("node-a"); node.Degraded {
+		t.Error("push node flagged as degraded")
+	}
+}
@@ -1,11 +1,23 @@
 package pantheon
 
+import (
+	"time"
+
+	"github.com/fleetcontrolsio/pantheon/pkg/hashring"
+)
+
 type MemberState string
 
 const (
 	MemberAlive   MemberState = "alive"
 	MemberDead    MemberState = "dead"
 	MemberSuspect MemberState = "suspect"
+	// MemberDraining; the node is alive but its keys are being migrated off before it leaves
+	MemberDraining MemberState = "draining"
+	// MemberLeft; the node left the cluster
+	MemberLeft MemberState = "left"
+	// MemberMaintenance; the node is out of service for planned work and is not checked
+	MemberMaintenance MemberState = "maintenance"
 )
 
 // MarshalBinary implements the encoding.BinaryMarshaler interface
@@ -35,6 +47,20 @@ type Member struct {
 	HeartbeatCount string
 	// HeartbeatFailures; the number of failed heartbeat requests
 	HeartbeatFailures string
-	// State; the state of the node: alive, dead, or suspect
+	// State; the state of the node: alive, suspect, dead, draining or maintenance
 	State MemberState
+	// StateSince; when the node entered its current state, zero if unknown
+	StateSince time.Time
+	// Weight; the relative capacity of the node in the hash ring
+	Weight int
+	// Topology; the region, zone and rack the node runs in
+	Topology hashring.Topology
+	// HealthCheck; the name of the health checker used for the node
+	HealthCheck string
+	// Heartbeat; the node's heartbeat policy, zero fields use the cluster defaults
+	Heartbeat HeartbeatPolicy
+	// Degraded; whether the node is alive but its p95 latency reached the degraded threshold
+	Degraded bool
+	// DrainRequested; whether the node was drained; it goes back to draining instead of alive
+	DrainRequested bool
 }
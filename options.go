@@ -38,6 +38,62 @@ type Options struct {
 	hashRing hashring.Ring
 	// hashringReplicaCount: number of virtual nodes per physical node in the hash ring
 	hashringReplicaCount int
+	// hashringHasher: the hash function used by the default hash ring
+	hashringHasher hashring.Hasher
+	// hashringLoadFactor: the capacity factor for consistent hashing with bounded loads
+	// 0 disables bounded loads
+	hashringLoadFactor float64
+	// hashringHashTag: extracts the hashed part of keys; nil disables hash tags
+	hashringHashTag hashring.HashTagFunc
+	// drainRate: the number of keys migrated off a draining node per second
+	drainRate int
+	// partitions: the number of fixed partitions the keyspace is split into
+	// 0 maps individual keys to nodes
+	partitions int
+	// hotKeyThreshold: the decayed number of lookups above which a key is hot
+	// 0 disables hot-key detection
+	hotKeyThreshold int
+	// hotKeySpread: the number of nodes a hot key is spread across
+	hotKeySpread int
+	// hotKeyHalfLife: the interval after which lookup counts are halved
+	hotKeyHalfLife time.Duration
+	// hotKeyLimit: the maximum number of hot keys listed by HotKeys
+	hotKeyLimit int
+	// healthCheckers: additional health checkers by name, selectable per node
+	healthCheckers map[string]HealthChecker
+	// failureDetector: a custom failure detector; nil selects a built-in one
+	failureDetector FailureDetector
+	// phiSuspectThreshold: the phi at which a node becomes suspect
+	// 0 disables the phi accrual detector
+	phiSuspectThreshold float64
+	// phiDeadThreshold: the phi at which a node is considered dead
+	phiDeadThreshold float64
+	// phiWindowSize: the number of heartbeat intervals kept per node
+	phiWindowSize int
+	// phiMinStdDev: the lower bound of the interval standard deviation
+	// 0 uses half of each node's heartbeat interval
+	phiMinStdDev time.Duration
+	// leaseTTL: how long a lease refreshed by a push-based heartbeat lasts
+	leaseTTL time.Duration
+	// indirectProbes: the number of nodes asked to probe a node that failed a direct check
+	// 0 disables indirect probes
+	indirectProbes int
+	// indirectProbePath: the path of the agent endpoint on each node
+	indirectProbePath string
+	// indirectProbeToken: the bearer token sent to agents, empty sends none
+	indirectProbeToken string
+	// heartbeatHistorySize: the number of probes kept in each node's history
+	heartbeatHistorySize int
+	// suspicionTimeout: how long a node may stay suspect before it is considered dead
+	// 0 uses each node's heartbeat interval times its max failures
+	suspicionTimeout time.Duration
+	// reviveSuccesses: the number of consecutive successful heartbeats a dead node needs to be revived
+	reviveSuccesses int
+	// degradedLatency: the p95 latency at which an alive node is reported as degraded
+	// 0 disables degraded events
+	degradedLatency time.Duration
+	// statsWindows: the windows node success rates are computed over
+	statsWindows []time.Duration
 }
 
 // NewOptions creates a new Options instance with default values
@@ -54,6 +110,26 @@ type Options struct {
 // - redisMaxRetries: 5
 // - redisRetryBackoff: 20 seconds
 // - hashringReplicaCount: 10
+// - hashringHasher: nil (xxHash64)
+// - hashringLoadFactor: 0 (bounded loads disabled)
+// - hashringHashTag: nil (hash tags disabled)
+// - drainRate: 100 keys per second
+// - partitions: 0 (per-key mappings)
+// - hotKeyThreshold: 0 (hot-key detection disabled)
+// - hotKeySpread: 0
+// - hotKeyHalfLife: 1 minute
+// - hotKeyLimit: 1000
+// - failureDetector: nil (failure counter)
+// - phiWindowSize: 100
+// - leaseTTL: 90 seconds
+// - indirectProbes: 0 (indirect probes disabled)
+// - indirectProbePath: "pantheon/probe"
+// - indirectProbeToken: "" (no token)
+// - heartbeatHistorySize: 100
+// - suspicionTimeout: 0 (heartbeat interval times max failures)
+// - reviveSuccesses: 1
+// - degradedLatency: 0 (degraded events disabled)
+// - statsWindows: 1, 5 and 15 minutes
 // - httpClient: nil
 // - hashRing: nil
 func NewOptions() *Options {
@@ -69,6 +145,15 @@ func NewOptions() *Options {
 		redisMaxRetries:      5,
 		redisRetryBackoff:    20 * time.Second,
 		hashringReplicaCount: 10, // Default to 10 virtual nodes per physical node
+		drainRate:            100,
+		hotKeyHalfLife:       time.Minute,
+		hotKeyLimit:          1000,
+		phiWindowSize:        100,
+		leaseTTL:             90 * time.Second,
+		indirectProbePath:    "pantheon/probe",
+		heartbeatHistorySize: 100,
+		reviveSuccesses:      1,
+		statsWindows:         []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute},
 	}
 }
 
@@ -137,6 +222,22 @@ func (o *Options) WithHTTPClient(client *http.Client) *Options {
 	return o
 }
 
+// WithHealthChecker registers a health checker under the given name
+// Nodes select it by joining with JoinOp.HealthCheck set to the name. The name
+// is stored with the node, so every process monitoring the cluster should
+// register the same checkers. Registering a built-in name ("http", "tcp",
+// "grpc", "push") replaces the built-in checker.
+func (o *Options) WithHealthChecker(name string, checker HealthChecker) *Options {
+	if o.healthCheckers == nil {
+		o.healthCheckers = make(map[string]HealthChecker)
+	}
+	o.healthCheckers[name] = checker
+	return o
+}
+
+// WithHashRing replaces the default virtual-node hash ring with any hashring.Ring implementation,
+// e.g. hashring.NewRendezvousRing()
+// The ring options (hasher, replica count, bounded load) only apply to the default ring.
 func (o *Options) WithHashRing(ring hashring.Ring) *Options {
 	o.hashRing = ring
 	return o
@@ -147,6 +248,157 @@ func (o *Options) WithHashRingReplicaCount(count int) *Options {
 	return o
 }
 
+// WithHashRingHasher sets the hash function of the default hash ring and of partitions
+// Every process sharing the ring must use the same hasher.
+func (o *Options) WithHashRingHasher(hasher hashring.Hasher) *Options {
+	o.hashringHasher = hasher
+	return o
+}
+
+// WithBoundedLoad enables consistent hashing with bounded loads on the default hash ring
+// No node is assigned more than ceil(factor * average) keys; 1.25 is a common choice.
+func (o *Options) WithBoundedLoad(factor float64) *Options {
+	o.hashringLoadFactor = factor
+	return o
+}
+
+// WithHashTags enables hash tags on the default hash ring
+// Only the part of a key returned by the extractor is hashed, so related keys
+// such as "{tenant42}:orders" and "{tenant42}:invoices" land on the same node.
+// A nil extractor selects hashring.RedisHashTag.
+func (o *Options) WithHashTags(extractor hashring.HashTagFunc) *Options {
+	if extractor == nil {
+		extractor = hashring.RedisHashTag
+	}
+	o.hashringHashTag = extractor
+	return o
+}
+
+// WithDrainRate sets how many keys per second are migrated off a draining node
+func (o *Options) WithDrainRate(keysPerSecond int) *Options {
+	o.drainRate = keysPerSecond
+	return o
+}
+
+// WithPartitions switches the cluster to fixed-partition mode
+// The keyspace is split into count partitions (e.g. 1024) and whole partitions
+// are assigned to nodes. Redis then stores one entry per partition instead of
+// one per key, and rebalancing moves partitions rather than key mappings.
+func (o *Options) WithPartitions(count int) *Options {
+	o.partitions = count
+	return o
+}
+
+// WithHotKeys enables hot-key detection
+// GetKeyNode counts lookups per key, and keys with at least threshold lookups
+// (halved every half-life) are flagged as hot. A spread above 1 serves each
+// hot key from its owner and its next ring successors, spread nodes in total.
+func (o *Options) WithHotKeys(threshold, spread int) *Options {
+	o.hotKeyThreshold = threshold
+	o.hotKeySpread = spread
+	return o
+}
+
+// WithHotKeyHalfLife sets how quickly lookup counts decay
+func (o *Options) WithHotKeyHalfLife(halfLife time.Duration) *Options {
+	o.hotKeyHalfLife = halfLife
+	return o
+}
+
+// WithHotKeyLimit sets the maximum number of hot keys listed by HotKeys
+// When more keys are hot, the coldest ones are dropped from the list. They are
+// still reported by IsHotKey and still spread.
+func (o *Options) WithHotKeyLimit(limit int) *Options {
+	o.hotKeyLimit = limit
+	return o
+}
+
+// WithFailureDetector replaces the built-in failure detectors
+func (o *Options) WithFailureDetector(detector FailureDetector) *Options {
+	o.failureDetector = detector
+	return o
+}
+
+// WithPhiAccrualDetector detects failures with a phi accrual detector instead
+// of counting failed heartbeats
+// Nodes become suspect when phi reaches suspectPhi and dead when it reaches
+// deadPhi. With the default window settings, a node with regular heartbeats
+// reaches a phi of about 0.3, 1.6, 4.7 and 10.8 after one to four consecutive
+// failed heartbeats, so (1, 8) suspects a node after two failures and
+// declares it dead after four.
+func (o *Options) WithPhiAccrualDetector(suspectPhi, deadPhi float64) *Options {
+	o.phiSuspectThreshold = suspectPhi
+	o.phiDeadThreshold = deadPhi
+	return o
+}
+
+// WithPhiAccrualWindow sets the number of heartbeat intervals the phi accrual
+// detector keeps per node and the lower bound of their standard deviation
+// A larger minimum standard deviation tolerates more jitter.
+func (o *Options) WithPhiAccrualWindow(size int, minStdDev time.Duration) *Options {
+	o.phiWindowSize = size
+	o.phiMinStdDev = minStdDev
+	return o
+}
+
+// WithLeaseTTL sets how long a lease refreshed by a push-based heartbeat lasts
+// Nodes should refresh their lease several times per TTL.
+func (o *Options) WithLeaseTTL(ttl time.Duration) *Options {
+	o.leaseTTL = ttl
+	return o
+}
+
+// WithIndirectProbes asks k other alive nodes to probe a node that failed a direct health check
+// The nodes must serve an Agent at the given path (empty keeps "pantheon/probe").
+// The node only counts as failed if every indirect probe fails as well.
+func (o *Options) WithIndirectProbes(k int, path string) *Options {
+	o.indirectProbes = k
+	if path != "" {
+		o.indirectProbePath = path
+	}
+	return o
+}
+
+// WithIndirectProbeToken sets the bearer token sent with indirect probe requests
+// The agents must be configured with the same token (see Agent.WithToken).
+func (o *Options) WithIndirectProbeToken(token string) *Options {
+	o.indirectProbeToken = token
+	return o
+}
+
+// WithHeartbeatHistorySize sets the number of probes kept in each node's heartbeat history
+func (o *Options) WithHeartbeatHistorySize(size int) *Options {
+	o.heartbeatHistorySize = size
+	return o
+}
+
+// WithSuspicionTimeout sets how long a node may stay suspect before it is considered dead
+// 0 uses each node's heartbeat interval times its max failures.
+func (o *Options) WithSuspicionTimeout(timeout time.Duration) *Options {
+	o.suspicionTimeout = timeout
+	return o
+}
+
+// WithReviveSuccesses sets the number of consecutive successful heartbeats a dead node needs to be revived
+func (o *Options) WithReviveSuccesses(successes int) *Options {
+	o.reviveSuccesses = successes
+	return o
+}
+
+// WithDegradedLatency sends a "degraded" event when the p95 latency of an alive node reaches latency
+// A "recovered" event follows once it drops below again. The p95 is computed
+// over the node's heartbeat history.
+func (o *Options) WithDegradedLatency(latency time.Duration) *Options {
+	o.degradedLatency = latency
+	return o
+}
+
+// WithStatsWindows sets the windows GetNodeStats computes success rates over
+func (o *Options) WithStatsWindows(windows ...time.Duration) *Options {
+	o.statsWindows = windows
+	return o
+}
+
 func (o *Options) Validate() error {
 	if o.prefix == "" {
 		return ErrInvalidPrefix
@@ -192,12 +444,78 @@ func (o *Options) Validate() error {
 		return ErrInvalidRedisRetryBackoff
 	}
 
-	if o.httpClient == nil {
-		return ErrInvalidHTTPClient
+	if o.hashringLoadFactor != 0 && o.hashringLoadFactor < 1 {
+		return ErrInvalidBoundedLoadFactor
+	}
+
+	if o.drainRate <= 0 {
+		return ErrInvalidDrainRate
+	}
+
+	if o.partitions < 0 {
+		return ErrInvalidPartitionCount
+	}
+
+	if o.hotKeyThreshold < 0 {
+		return ErrInvalidHotKeyThreshold
+	}
+
+	if o.hotKeySpread < 0 {
+		return ErrInvalidHotKeySpread
+	}
+
+	if o.hotKeyHalfLife <= 0 {
+		return ErrInvalidHotKeyHalfLife
 	}
 
-	if o.hashRing == nil {
-		return ErrInvalidHashRing
+	if o.hotKeyLimit <= 0 {
+		return ErrInvalidHotKeyLimit
+	}
+
+	if o.phiSuspectThreshold < 0 || o.phiDeadThreshold < o.phiSuspectThreshold {
+		return ErrInvalidPhiThreshold
+	}
+
+	if o.phiWindowSize <= 0 {
+		return ErrInvalidPhiWindowSize
+	}
+
+	if o.phiMinStdDev < 0 {
+		return ErrInvalidPhiMinStdDev
+	}
+
+	if o.leaseTTL <= 0 {
+		return ErrInvalidLeaseTTL
+	}
+
+	if o.indirectProbes < 0 {
+		return ErrInvalidIndirectProbes
+	}
+
+	if o.heartbeatHistorySize <= 0 {
+		return ErrInvalidHeartbeatHistorySize
+	}
+
+	if o.suspicionTimeout < 0 {
+		return ErrInvalidSuspicionTimeout
+	}
+
+	if o.reviveSuccesses <= 0 {
+		return ErrInvalidReviveSuccesses
+	}
+
+	if o.degradedLatency < 0 {
+		return ErrInvalidDegradedLatency
+	}
+
+	for _, window := range o.statsWindows {
+		if window <= 0 {
+			return ErrInvalidStatsWindow
+		}
+	}
+
+	if o.httpClient == nil {
+		return ErrInvalidHTTPClient
 	}
 
 	return nil
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fleetcontrolsio/pantheon/pkg/hashring"
@@ -20,8 +22,22 @@ type Pantheon struct {
 	hashRing hashring.Ring
 	// name; the name of the cluster
 	name string
-	// hearbeat; the interval at which the cluster will send heartbeat messages to other nodes
+	// hearbeat; ticks at the shortest heartbeat interval of any node
 	hearbeat *time.Ticker
+	// heartbeatInterval; the default interval at which nodes are checked
+	heartbeatInterval time.Duration
+	// heartbeatTick; the current period of the heartbeat ticker
+	heartbeatTick time.Duration
+	// heartbeatDue; when each node is next checked, only used by the heartbeat loop
+	heartbeatDue map[string]time.Time
+	// heartbeatProbing; the nodes with a check in flight
+	heartbeatProbing map[string]bool
+	// heartbeatMu; protects heartbeatProbing
+	heartbeatMu sync.Mutex
+	// heartbeatSem; limits the number of concurrent heartbeat checks
+	heartbeatSem chan struct{}
+	// heartbeatReschedule; wakes the heartbeat loop when a node joins with its own policy
+	heartbeatReschedule chan struct{}
 	// heartbeatConcurrency; the number of concurrent heartbeat checks
 	heartbeatConcurrency int
 	// heartbeatTimeout; the timeout for heartbeat checks
@@ -29,12 +45,42 @@ type Pantheon struct {
 	heartbeatTimeout time.Duration
 	// heartbeatMaxFailures; the maximum number of failed heartbeat requests before a node is considered dead
 	heartbeatMaxFailures int
+	// drainRate; the number of keys migrated off a draining node per second
+	drainRate int
 	// heartbeatEventCh; a channel to send heartbeat events
 	heartbeatEventCh chan HearbeatEvent
 	// eventsCh; a channel to send cluster events
 	EventsCh chan PantheonEvent
 	// started; a flag to indicate if the cluster has been started
 	started bool
+	// distributed; the hash ring snapshot used by the last call to Distribute
+	distributed atomic.Pointer[hashring.Snapshot]
+	// partitioner; maps keys to fixed partitions, nil unless partition mode is enabled
+	partitioner *hashring.Partitioner
+	// hotKeys; counts key lookups, nil unless hot-key detection is enabled
+	hotKeys *hotKeyTracker
+	// hotKeySpread; the number of nodes a hot key is spread across
+	hotKeySpread int
+	// healthCheckers; the health checkers nodes can select, by name
+	healthCheckers map[string]HealthChecker
+	// failureDetector; decides from heartbeat results whether nodes are alive, suspect or dead
+	failureDetector FailureDetector
+	// stateMachine; moves nodes between states based on the failure detector
+	stateMachine StateMachine
+	// leaseTTL; how long a lease refreshed by a push-based heartbeat lasts
+	leaseTTL time.Duration
+	// indirectProbes; the number of nodes asked to probe a node that failed a direct check
+	indirectProbes int
+	// indirectProbePath; the path of the agent endpoint on each node
+	indirectProbePath string
+	// indirectProbeToken; the bearer token sent to agents
+	indirectProbeToken string
+	// heartbeatHistorySize; the number of probes kept in each node's history
+	heartbeatHistorySize int
+	// degradedLatency; the p95 latency at which an alive node is reported as degraded
+	degradedLatency time.Duration
+	// statsWindows; the windows node success rates are computed over
+	statsWindows []time.Duration
 }
 
 type JoinOp struct {
@@ -46,6 +92,19 @@ type JoinOp struct {
 	Port int
 	// Path: the path on the node to make the heartbeat request to
 	Path string
+	// Weight; the relative capacity of the node (defaults to 1)
+	// A node with weight 4 receives roughly four times the keys of a node with weight 1
+	Weight int
+	// Topology; the region, zone and rack of the node (optional)
+	// Replica sets are spread across zones and racks when set
+	Topology hashring.Topology
+	// HealthCheck; the name of the health checker used for the node (defaults to "http")
+	// Built-in checkers are "http", "tcp", "grpc" and "push"; others are registered with
+	// Options.WithHealthChecker
+	HealthCheck string
+	// Heartbeat; overrides the cluster's heartbeat interval, timeout and max failures
+	// for the node (optional)
+	Heartbeat HeartbeatPolicy
 }
 
 // New create a new Pantheon instance
@@ -68,12 +127,63 @@ func New(ctx context.Context, options *Options) (*Pantheon, error) {
 
 	storage := NewStorage(options.prefix, options.name, redisClient)
 
+	ringOptions := []hashring.Option{
+		hashring.WithHasher(options.hashringHasher),
+		hashring.WithBoundedLoad(options.hashringLoadFactor),
+	}
+	if options.hashringHashTag != nil {
+		ringOptions = append(ringOptions, hashring.WithHashTags(options.hashringHashTag))
+	}
+
 	// Create a hash ring if one is not provided
 	var ring hashring.Ring
 	if options.hashRing != nil {
 		ring = options.hashRing
 	} else {
-		ring = hashring.NewHashRing(options.hashringReplicaCount)
+		ring = hashring.NewHashRing(options.hashringReplicaCount, ringOptions...)
+	}
+
+	// Split the keyspace into fixed partitions if requested
+	var partitioner *hashring.Partitioner
+	if options.partitions > 0 {
+		partitioner, err = hashring.NewPartitioner(options.partitions, ringOptions...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Built-in health checkers, overridden by registered ones
+	healthCheckers := map[string]HealthChecker{
+		HealthCheckHTTP: &HTTPChecker{Client: options.httpClient},
+		HealthCheckTCP:  TCPChecker{},
+		HealthCheckGRPC: &GRPCChecker{},
+		HealthCheckPush: &leaseChecker{storage: storage},
+	}
+	for name, checker := range options.healthCheckers {
+		healthCheckers[name] = checker
+	}
+
+	// Count failed heartbeats unless another failure detector is configured
+	var detector FailureDetector = &counterDetector{
+		storage:     storage,
+		maxFailures: options.heartbeatMaxFailures,
+	}
+	if options.failureDetector != nil {
+		detector = options.failureDetector
+	} else if options.phiSuspectThreshold > 0 {
+		detector = &phiAccrualDetector{
+			storage:          storage,
+			suspectPhi:       options.phiSuspectThreshold,
+			deadPhi:          options.phiDeadThreshold,
+			windowSize:       options.phiWindowSize,
+			minStdDev:        options.phiMinStdDev,
+			expectedInterval: options.hearbeatInterval,
+		}
+	}
+
+	var hotKeys *hotKeyTracker
+	if options.hotKeyThreshold > 0 {
+		hotKeys = newHotKeyTracker(options.hotKeyThreshold, options.hotKeyLimit, options.hotKeyHalfLife)
 	}
 
 	return &Pantheon{
@@ -82,13 +192,36 @@ func New(ctx context.Context, options *Options) (*Pantheon, error) {
 		storage:              storage,
 		http:                 options.httpClient,
 		hearbeat:             time.NewTicker(options.hearbeatInterval),
+		heartbeatInterval:    options.hearbeatInterval,
+		heartbeatTick:        options.hearbeatInterval,
+		heartbeatDue:         make(map[string]time.Time),
+		heartbeatProbing:     make(map[string]bool),
+		heartbeatSem:         make(chan struct{}, options.heartbeatConcurrency),
+		heartbeatReschedule:  make(chan struct{}, 1),
 		heartbeatTimeout:     options.heartbeatTimeout,
 		heartbeatConcurrency: options.heartbeatConcurrency,
 		heartbeatMaxFailures: options.heartbeatMaxFailures,
+		drainRate:            options.drainRate,
 		heartbeatEventCh:     make(chan HearbeatEvent),
 		hashRing:             ring,
 		EventsCh:             make(chan PantheonEvent),
 		started:              false,
+		partitioner:          partitioner,
+		hotKeys:              hotKeys,
+		hotKeySpread:         options.hotKeySpread,
+		healthCheckers:       healthCheckers,
+		failureDetector:      detector,
+		stateMachine: StateMachine{
+			SuspicionTimeout: options.suspicionTimeout,
+			ReviveSuccesses:  options.reviveSuccesses,
+		},
+		leaseTTL:             options.leaseTTL,
+		indirectProbes:       options.indirectProbes,
+		indirectProbePath:    options.indirectProbePath,
+		indirectProbeToken:   options.indirectProbeToken,
+		heartbeatHistorySize: options.heartbeatHistorySize,
+		degradedLatency:      options.degradedLatency,
+		statsWindows:         options.statsWindows,
 	}, nil
 }
 
@@ -99,8 +232,18 @@ func (c *Pantheon) Start() error {
 		return nil
 	}
 
+	// Pick up the ring persisted by other processes, if any
+	if err := c.LoadRing(); err != nil {
+		return err
+	}
+
 	c.started = true
 
+	// Finish draining nodes whose migration was interrupted by a restart
+	if err := c.resumeDraining(); err != nil {
+		return err
+	}
+
 	// handle the heartbeat events
 	go func() {
 		for {
@@ -108,8 +251,7 @@ func (c *Pantheon) Start() error {
 			case event := <-c.heartbeatEventCh:
 				c.handleHeartbeatEvent(event)
 			case <-c.ctx.Done():
-				// Close the heartbeat event channel
-				close(c.heartbeatEventCh)
+				// The channel is left open; checks still in flight give up on the context
 				return
 			}
 		}
@@ -120,10 +262,10 @@ func (c *Pantheon) Start() error {
 		for {
 			select {
 			case <-c.hearbeat.C:
-				ctx, cancel := context.WithTimeout(c.ctx, c.heartbeatTimeout)
-				defer cancel()
-
-				c.performHeartbeat(ctx)
+				c.performHeartbeat()
+			case <-c.heartbeatReschedule:
+				// Pick up a shorter interval without waiting for the current tick
+				c.performHeartbeat()
 			case <-c.ctx.Done():
 				c.hearbeat.Stop()
 				return
@@ -154,22 +296,67 @@ func (c *Pantheon) Join(op *JoinOp) error {
 	}
 
 	// upsert the node in the storage
-	err := c.storage.AddNode(c.ctx, op.ID, op.Address, op.Path, op.Port)
+	weight := op.Weight
+	if weight <= 0 {
+		weight = 1
+	}
+
+	healthCheck := op.HealthCheck
+	if healthCheck == "" {
+		healthCheck = HealthCheckHTTP
+	}
+
+	if _, ok := c.healthChecker(healthCheck); !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownHealthCheck, healthCheck)
+	}
+
+	if err := op.Heartbeat.validate(c.heartbeatPolicy(&Member{})); err != nil {
+		return err
+	}
+
+	err := c.storage.AddNode(c.ctx, op.ID, op.Address, op.Path, op.Port, weight, op.Topology, healthCheck, op.Heartbeat)
+	if err != nil {
+		return err
+	}
+
+	// Nodes using push-based heartbeats start with a fresh lease
+	if healthCheck == HealthCheckPush {
+		if err := c.storage.RenewLease(c.ctx, op.ID, c.leaseTTL); err != nil {
+			return err
+		}
+	}
+
+	// A rejoining node keeps its stored state, e.g. dead until heartbeats revive it
+	member, err := c.storage.GetNode(c.ctx, op.ID)
 	if err != nil {
 		return err
 	}
+	status := hashring.NodeStatusActive
+	if member != nil {
+		status = memberRingStatus(member.State)
+	}
 
 	addr := fmt.Sprintf("%s:%d", op.Address, op.Port)
+	node := &hashring.Node{
+		ID:       op.ID,
+		Address:  addr,
+		Status:   status,
+		Weight:   weight,
+		Topology: op.Topology,
+	}
+
 	// Add the node to the hash ring
-	err = c.hashRing.AddNode(&hashring.Node{
-		ID:      op.ID,
-		Address: addr,
-		Status:  hashring.NodeStatusActive,
-	})
+	err = c.hashRing.AddNode(node)
+	if err == hashring.ErrNodeExists {
+		// The node is already in the ring, e.g. one loaded from Redis; refresh it
+		err = c.hashRing.UpdateNode(node)
+	}
 	if err != nil {
 		return err
 	}
 
+	c.saveRing()
+
 	// Send a joined event
 	if c.EventsCh != nil {
 		c.EventsCh <- PantheonEvent{
@@ -178,6 +365,14 @@ func (c *Pantheon) Join(op *JoinOp) error {
 		}
 	}
 
+	// Nodes with their own interval may need the heartbeat loop to tick sooner
+	if op.Heartbeat.Interval > 0 {
+		select {
+		case c.heartbeatReschedule <- struct{}{}:
+		default:
+		}
+	}
+
 	// Immediately ping the node to check its health
 	go func() {
 		if err := c.PingNode(op.ID); err != nil {
@@ -207,6 +402,10 @@ func (c *Pantheon) Leave(id string) error {
 		return fmt.Errorf("node %s not found", id)
 	}
 
+	if !CanTransition(node.State, MemberLeft) {
+		return fmt.Errorf("%w: node %s from %s to %s", ErrInvalidTransition, id, node.State, MemberLeft)
+	}
+
 	// Remove the node from the cluster
 	err = c.storage.RemoveNode(c.ctx, id)
 	if err != nil {
@@ -219,6 +418,8 @@ func (c *Pantheon) Leave(id string) error {
 		return err
 	}
 
+	c.saveRing()
+
 	// Send a left event
 	if c.EventsCh != nil {
 		c.EventsCh <- PantheonEvent{
@@ -230,3 +431,36 @@ func (c *Pantheon) Leave(id string) error {
 	fmt.Printf("Node %s left the cluster\n", id)
 	return nil
 }
+
+// SetNodeWeight changes the weight of a node at runtime
+// Only the keys that fall on the added or removed virtual nodes change owner.
+// Call Distribute afterwards to move the affected key mappings.
+func (c *Pantheon) SetNodeWeight(id string, weight int) error {
+	if !c.started {
+		return fmt.Errorf("cluster not started")
+	}
+
+	if weight <= 0 {
+		return fmt.Errorf("weight must be greater than 0")
+	}
+
+	node, err := c.storage.GetNode(c.ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if node == nil {
+		return fmt.Errorf("node %s not found", id)
+	}
+
+	if err := c.storage.UpdateNodeWeight(c.ctx, id, weight); err != nil {
+		return err
+	}
+
+	if err := c.hashRing.UpdateNodeWeight(id, weight); err != nil {
+		return err
+	}
+
+	c.saveRing()
+	return nil
+}
@@ -0,0 +1,164 @@
+package pantheon
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/fleetcontrolsio/pantheon/pkg/hashring"
+)
+
+// RebalancePartitions assigns every partition to its owner on the current hash ring
+// Only the partitions whose owner changed are written to Redis. It is called
+// automatically whenever the ring changes, so calling it directly is only
+// needed to repair the table.
+func (c *Pantheon) RebalancePartitions() error {
+	if c.partitioner == nil {
+		return ErrPartitionsDisabled
+	}
+
+	owners, err := c.partitioner.Assign(c.hashRing)
+	if err != nil {
+		return fmt.Errorf("error assigning partitions: %w", err)
+	}
+
+	current, err := c.storage.GetPartitionTable(c.ctx)
+	if err != nil {
+		return err
+	}
+
+	moved := make(map[int]string)
+	for partition, nodeID := range owners {
+		if current[partition] != nodeID {
+			moved[partition] = nodeID
+		}
+	}
+
+	if err := c.storage.SetPartitionOwners(c.ctx, moved); err != nil {
+		return err
+	}
+
+	if len(moved) > 0 {
+		fmt.Printf("Rebalanced partitions: %d of %d moved\n", len(moved), len(owners))
+	}
+
+	return nil
+}
+
+// GetKeyPartition returns the partition a key belongs to
+func (c *Pantheon) GetKeyPartition(key string) (int, error) {
+	if c.partitioner == nil {
+		return 0, ErrPartitionsDisabled
+	}
+
+	return c.partitioner.Partition(key), nil
+}
+
+// GetPartitionNode returns the node that owns a partition
+func (c *Pantheon) GetPartitionNode(partition int) (string, error) {
+	if !c.started {
+		return "", fmt.Errorf("cluster not started")
+	}
+
+	if c.partitioner == nil {
+		return "", ErrPartitionsDisabled
+	}
+
+	if partition < 0 || partition >= c.partitioner.Count() {
+		return "", fmt.Errorf("partition %d out of range", partition)
+	}
+
+	nodeID, err := c.storage.GetPartitionOwner(c.ctx, partition)
+	if err != nil {
+		return "", err
+	}
+
+	if nodeID != "" {
+		return nodeID, nil
+	}
+
+	// The table has not been written yet; fall back to the hash ring
+	return c.ringPartitionOwner(partition)
+}
+
+// GetNodePartitions returns the partitions owned by a node, in ascending order
+func (c *Pantheon) GetNodePartitions(nodeID string) ([]int, error) {
+	if !c.started {
+		return nil, fmt.Errorf("cluster not started")
+	}
+
+	if c.partitioner == nil {
+		return nil, ErrPartitionsDisabled
+	}
+
+	table, err := c.storage.GetPartitionTable(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make([]int, 0)
+	for partition, owner := range table {
+		if owner == nodeID && partition < c.partitioner.Count() {
+			partitions = append(partitions, partition)
+		}
+	}
+	sort.Ints(partitions)
+
+	return partitions, nil
+}
+
+// getPartitionedKeysNodes returns the owner of each key's partition
+// The partition table is read once for the whole batch.
+func (c *Pantheon) getPartitionedKeysNodes(keys []string) (map[string]string, error) {
+	table, err := c.storage.GetPartitionTable(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(keys))
+	for _, key := range keys {
+		partition := c.partitioner.Partition(key)
+
+		nodeID, ok := table[partition]
+		if !ok {
+			// The table has not been written yet; fall back to the hash ring
+			nodeID, err = c.ringPartitionOwner(partition)
+			if err != nil {
+				return nil, err
+			}
+			table[partition] = nodeID
+		}
+
+		result[key] = nodeID
+	}
+
+	return result, nil
+}
+
+// getPartitionReplicas returns rf distinct nodes for the partition of a key
+// The first node is the partition owner on the current ring. Replica sets are
+// derived from the ring on every call rather than stored per key.
+func (c *Pantheon) getPartitionReplicas(key string, rf int) ([]string, error) {
+	partition := c.partitioner.Partition(key)
+
+	nodes, err := c.hashRing.GetNodesN(hashring.PartitionKey(partition), rf)
+	if err != nil {
+		return nil, fmt.Errorf("error determining replicas for partition %d: %w", partition, err)
+	}
+
+	replicas := make([]string, len(nodes))
+	for i, node := range nodes {
+		replicas[i] = node.ID
+	}
+
+	return replicas, nil
+}
+
+// ringPartitionOwner returns the owner of a partition on the current hash ring
+func (c *Pantheon) ringPartitionOwner(partition int) (string, error) {
+	node, err := c.hashRing.GetNode(hashring.PartitionKey(partition))
+	if err != nil {
+		return "", fmt.Errorf("error determining node for partition %d: %w", partition, err)
+	}
+
+	return node.ID, nil
+}
@@ -0,0 +1,93 @@
+package hashring
+
+import "math"
+
+// BoundedLoad reports whether bounded-load mode is enabled
+func (h *HashRing) BoundedLoad() bool {
+	return h.snapshot.Load().config.loadFactor > 0
+}
+
+// SetLoads replaces the current per-node key counts
+// Loads for nodes that are not in the ring are ignored.
+func (h *HashRing) SetLoads(loads map[string]int) {
+	snapshot := h.snapshot.Load()
+
+	h.loadMu.Lock()
+	defer h.loadMu.Unlock()
+
+	h.loads = make(map[string]int, len(snapshot.nodes))
+	h.totalLoad = 0
+	for nodeID, load := range loads {
+		if _, exists := snapshot.nodes[nodeID]; !exists || load <= 0 {
+			continue
+		}
+		h.loads[nodeID] = load
+		h.totalLoad += load
+	}
+}
+
+// AddLoad adjusts the key count of a node by delta
+func (h *HashRing) AddLoad(nodeID string, delta int) {
+	if _, exists := h.snapshot.Load().nodes[nodeID]; !exists {
+		return
+	}
+
+	h.loadMu.Lock()
+	defer h.loadMu.Unlock()
+
+	load := h.loads[nodeID] + delta
+	if load < 0 {
+		load = 0
+	}
+	h.totalLoad += load - h.loads[nodeID]
+	h.loads[nodeID] = load
+}
+
+// capacity returns the maximum number of keys a node may hold once one more key is placed
+func (s *Snapshot) capacity(node *Node, totalLoad, totalWeight int) int {
+	average := float64(totalLoad+1) * float64(node.EffectiveWeight()) / float64(totalWeight)
+	return int(math.Ceil(s.config.loadFactor * average))
+}
+
+// getNodeWithCapacity walks the ring from the key and returns the first
+// available node whose load stays within its bound after taking one more key
+func (s *Snapshot) getNodeWithCapacity(key string, loads map[string]int, totalLoad int) (*Node, error) {
+	if len(s.nodes) == 0 {
+		return nil, ErrNoNodes
+	}
+
+	totalWeight := 0
+	for _, node := range s.nodes {
+		if node.IsAvailable() {
+			totalWeight += node.EffectiveWeight()
+		}
+	}
+
+	if totalWeight == 0 {
+		return nil, ErrNoActiveNodes
+	}
+
+	startIdx := s.search(s.config.hashKey(key))
+
+	checked := make(map[string]bool, len(s.nodes))
+	for i := 0; i < len(s.hashes) && len(checked) < len(s.nodes); i++ {
+		nodeID := s.owners[(startIdx+i)%len(s.hashes)]
+		if checked[nodeID] {
+			continue
+		}
+		checked[nodeID] = true
+
+		node := s.nodes[nodeID]
+		if !node.IsAvailable() {
+			continue
+		}
+
+		if loads[nodeID]+1 <= s.capacity(node, totalLoad, totalWeight) {
+			return node, nil
+		}
+	}
+
+	// Every node is at capacity, which can only happen if loads were reported
+	// inconsistently; fall back to plain consistent hashing
+	return s.getNextAvailableNode(startIdx)
+}
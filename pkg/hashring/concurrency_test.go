@@ -0,0 +1,163 @@
+package hashring
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestHashRingConcurrentAccess mixes lookups with status, weight and membership
+// changes, as heartbeats do; run it with -race
+func TestHashRingConcurrentAccess(t *testing.T) {
+	ring := NewHashRing(20)
+	for i := 0; i < 5; i++ {
+		if err := ring.AddNode(&Node{ID: fmt.Sprintf("node-%d", i), Status: NodeStatusActive}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const iterations = 500
+	var wg sync.WaitGroup
+
+	// Readers
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				key := fmt.Sprintf("key-%d-%d", r, i)
+				if _, err := ring.GetNode(key); err != nil && err != ErrNoActiveNodes {
+					t.Error(err)
+					return
+				}
+				if _, err := ring.GetNodesN(key, 3); err != nil && err != ErrNoActiveNodes {
+					t.Error(err)
+					return
+				}
+				for _, node := range ring.GetNodes() {
+					_ = node.Status
+				}
+			}
+		}(r)
+	}
+
+	// Heartbeats flipping node status
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			status := NodeStatusActive
+			if i%2 == 0 {
+				status = NodeStatusInactive
+			}
+			if err := ring.UpdateNodeStatus(fmt.Sprintf("node-%d", i%5), status); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	// Weight changes
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			if err := ring.UpdateNodeWeight(fmt.Sprintf("node-%d", i%5), 1+i%4); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	// Nodes joining and leaving
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			id := fmt.Sprintf("extra-%d", i%3)
+			if err := ring.AddNode(&Node{ID: id, Status: NodeStatusActive}); err == ErrNodeExists {
+				if err := ring.RemoveNode(id); err != nil {
+					t.Error(err)
+					return
+				}
+			} else if err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+}
+
+func TestRingResultsAreCopies(t *testing.T) {
+	maglev, err := NewMaglevRing(251)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rings := map[string]Ring{
+		"hashring":   NewHashRing(10),
+		"bounded":    NewHashRing(10, WithBoundedLoad(1.25)),
+		"rendezvous": NewRendezvousRing(),
+		"jump":       NewJumpRing(),
+		"maglev":     maglev,
+	}
+
+	for name, ring := range rings {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 3; i++ {
+				if err := ring.AddNode(&Node{ID: fmt.Sprintf("node-%d", i), Address: "10.0.0.1:80", Status: NodeStatusActive}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var snapshot *Snapshot
+			if h, ok := ring.(*HashRing); ok {
+				snapshot = h.Snapshot()
+			}
+
+			// Scribble over everything a lookup returns
+			node, err := ring.GetNode("key")
+			if err != nil {
+				t.Fatal(err)
+			}
+			owner := node.ID
+			node.Status = NodeStatusInactive
+			node.Address = "corrupted"
+
+			replicas, err := ring.GetNodesN("key", 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, replica := range replicas {
+				replica.Status = NodeStatusInactive
+			}
+
+			batch, err := ring.GetNodesForKeys([]string{"key"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			batch["key"].Status = NodeStatusInactive
+
+			for _, n := range ring.GetNodes() {
+				n.Status = NodeStatusInactive
+			}
+
+			node, err = ring.GetNode("key")
+			if err != nil {
+				t.Fatalf("ring corrupted by caller: %s", err)
+			}
+			if node.ID != owner || node.Address != "10.0.0.1:80" || !node.IsAvailable() {
+				t.Fatalf("ring corrupted by caller: %+v", node)
+			}
+
+			if snapshot != nil {
+				node, err := snapshot.GetNode("key")
+				if err != nil || node.ID != owner || !node.IsAvailable() {
+					t.Fatalf("snapshot corrupted by caller: %+v, %v", node, err)
+				}
+			}
+		})
+	}
+}
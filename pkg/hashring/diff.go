@@ -0,0 +1,158 @@
+package hashring
+
+import (
+	"math"
+	"sort"
+)
+
+// MovedRange is a contiguous range of the hash space whose owner changed
+// between two snapshots. Start and End are inclusive.
+type MovedRange struct {
+	// Start is the first hash in the range
+	Start uint64
+
+	// End is the last hash in the range
+	End uint64
+
+	// OldOwner is the ID of the node that owned the range before, or "" if no node was available
+	OldOwner string
+
+	// NewOwner is the ID of the node that owns the range now, or "" if no node is available
+	NewOwner string
+}
+
+// Contains reports whether the hash falls into the range
+func (r MovedRange) Contains(hash uint64) bool {
+	return hash >= r.Start && hash <= r.End
+}
+
+// MovedRanges is a list of moved ranges sorted by Start, as returned by Diff
+type MovedRanges []MovedRange
+
+// Lookup returns the moved range containing the hash, if any
+func (m MovedRanges) Lookup(hash uint64) (MovedRange, bool) {
+	idx := sort.Search(len(m), func(i int) bool {
+		return m[i].End >= hash
+	})
+
+	if idx < len(m) && m[idx].Contains(hash) {
+		return m[idx], true
+	}
+
+	return MovedRange{}, false
+}
+
+// Diff returns the hash ranges whose owner differs between two snapshots
+// Ownership takes node status into account: a range owned by a node that is
+// not available belongs to the next available node on the ring. Adjacent
+// ranges with the same old and new owner are merged. Both snapshots must use
+// the same hash function, otherwise ErrHasherMismatch is returned.
+func Diff(from, to *Snapshot) (MovedRanges, error) {
+	if from.config != to.config {
+		fromName, fromErr := hasherName(from.config.hasher)
+		toName, toErr := hasherName(to.config.hasher)
+		if fromErr != nil || toErr != nil || fromName != toName {
+			return nil, ErrHasherMismatch
+		}
+	}
+
+	// Every virtual node of either ring is a potential ownership boundary
+	boundaries := make([]uint64, 0, len(from.hashes)+len(to.hashes))
+	boundaries = append(boundaries, from.hashes...)
+	boundaries = append(boundaries, to.hashes...)
+	sort.Slice(boundaries, func(i, j int) bool { return boundaries[i] < boundaries[j] })
+	boundaries = dedupeSorted(boundaries)
+
+	if len(boundaries) == 0 {
+		return MovedRanges{}, nil
+	}
+
+	fromOwners := from.effectiveOwners()
+	toOwners := to.effectiveOwners()
+
+	ownersAt := func(hash uint64) (string, string) {
+		return from.ownerAt(fromOwners, hash), to.ownerAt(toOwners, hash)
+	}
+
+	moved := make(MovedRanges, 0)
+	add := func(start, end uint64, oldOwner, newOwner string) {
+		if oldOwner == newOwner {
+			return
+		}
+		if n := len(moved); n > 0 && moved[n-1].End+1 == start &&
+			moved[n-1].OldOwner == oldOwner && moved[n-1].NewOwner == newOwner {
+			moved[n-1].End = end
+			return
+		}
+		moved = append(moved, MovedRange{Start: start, End: end, OldOwner: oldOwner, NewOwner: newOwner})
+	}
+
+	// [0, first boundary] wraps around to the first virtual node
+	oldOwner, newOwner := ownersAt(boundaries[0])
+	add(0, boundaries[0], oldOwner, newOwner)
+
+	for i := 1; i < len(boundaries); i++ {
+		oldOwner, newOwner := ownersAt(boundaries[i])
+		add(boundaries[i-1]+1, boundaries[i], oldOwner, newOwner)
+	}
+
+	// (last boundary, max] is owned by the first virtual node as well
+	if last := boundaries[len(boundaries)-1]; last < math.MaxUint64 {
+		oldOwner, newOwner := ownersAt(0)
+		add(last+1, math.MaxUint64, oldOwner, newOwner)
+	}
+
+	return moved, nil
+}
+
+// Hash returns the ring position of a key in this snapshot
+func (s *Snapshot) Hash(key string) uint64 {
+	return s.config.hashKey(key)
+}
+
+// effectiveOwners returns, for every virtual node, the ID of the first
+// available node at or after it on the ring ("" if none is available)
+func (s *Snapshot) effectiveOwners() []string {
+	owners := make([]string, len(s.hashes))
+	if len(s.hashes) == 0 {
+		return owners
+	}
+
+	// Walk backwards twice around the ring so that every position sees the
+	// next available owner, including across the wrap-around
+	next := ""
+	for i := 2*len(s.hashes) - 1; i >= 0; i-- {
+		idx := i % len(s.hashes)
+		if node := s.nodes[s.owners[idx]]; node != nil && node.IsAvailable() {
+			next = node.ID
+		}
+		if i < len(s.hashes) {
+			owners[idx] = next
+		}
+	}
+
+	return owners
+}
+
+// ownerAt returns the effective owner of a hash given the precomputed effective owners
+func (s *Snapshot) ownerAt(owners []string, hash uint64) string {
+	if len(s.hashes) == 0 {
+		return ""
+	}
+	return owners[s.search(hash)]
+}
+
+// dedupeSorted removes adjacent duplicates from a sorted slice in place
+func dedupeSorted(values []uint64) []uint64 {
+	if len(values) == 0 {
+		return values
+	}
+
+	out := values[:1]
+	for _, v := range values[1:] {
+		if v != out[len(out)-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -10,3 +10,24 @@ var ErrNodeExists = errors.New("node already exists in the hash ring")
 
 // ErrNodeNotFound is returned when attempting to operate on a node that doesn't exist
 var ErrNodeNotFound = errors.New("node not found in the hash ring")
+
+// ErrNoActiveNodes is returned when the ring has nodes but none of them is available
+var ErrNoActiveNodes = errors.New("no active nodes available")
+
+// ErrInvalidTableSize is returned when a lookup table size is not a prime number
+var ErrInvalidTableSize = errors.New("table size must be a prime number")
+
+// ErrUnknownHasher is returned when a hasher has no registered name
+var ErrUnknownHasher = errors.New("unknown hasher")
+
+// ErrStaleEpoch is returned when restoring a ring state older than the ring's current state
+var ErrStaleEpoch = errors.New("ring state is older than the current epoch")
+
+// ErrInvalidState is returned when serialized ring state cannot be decoded
+var ErrInvalidState = errors.New("invalid ring state")
+
+// ErrHasherMismatch is returned when comparing snapshots that hash keys differently
+var ErrHasherMismatch = errors.New("snapshots use different hash functions")
+
+// ErrInvalidPartitionCount is returned when a partitioner is created with fewer than one partition
+var ErrInvalidPartitionCount = errors.New("partition count must be greater than 0")
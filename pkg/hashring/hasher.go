@@ -0,0 +1,277 @@
+package hashring
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// Hasher maps arbitrary bytes onto the 64-bit ring space
+type Hasher interface {
+	// Hash returns the 64-bit hash of the given data
+	Hash(data []byte) uint64
+}
+
+// NamedHasher is a Hasher that can be identified by name
+// Rings record the name of their hasher when serialized, so only named hashers
+// (the built-in ones or ones added with RegisterHasher) can be restored.
+type NamedHasher interface {
+	Hasher
+
+	// Name returns the registered name of the hasher
+	Name() string
+}
+
+// HasherFunc adapts an ordinary function to the Hasher interface
+type HasherFunc func(data []byte) uint64
+
+// Hash calls f(data)
+func (f HasherFunc) Hash(data []byte) uint64 {
+	return f(data)
+}
+
+// XXHash64 hashes data using xxHash64
+type XXHash64 struct{}
+
+// Hash implements the Hasher interface
+func (XXHash64) Hash(data []byte) uint64 {
+	return xxhash.Sum64(data)
+}
+
+// Name implements the NamedHasher interface
+func (XXHash64) Name() string {
+	return "xxhash64"
+}
+
+// FNV1a hashes data using the 64-bit FNV-1a algorithm
+// FNV-1a mixes its last bytes poorly into the high bits, so short keys that
+// differ only at the end (such as virtual node names) cluster on the ring and
+// nodes receive very uneven shares. Prefer XXHash64 or Murmur3 unless FNV-1a
+// is needed for compatibility with another system.
+type FNV1a struct{}
+
+// Hash implements the Hasher interface
+func (FNV1a) Hash(data []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(data)
+	return h.Sum64()
+}
+
+// Name implements the NamedHasher interface
+func (FNV1a) Name() string {
+	return "fnv1a"
+}
+
+// Murmur3 hashes data using MurmurHash3 (x64, 128-bit variant) and returns the
+// first 64 bits of the digest
+type Murmur3 struct {
+	// Seed is the seed passed to the hash function
+	Seed uint32
+}
+
+// Name implements the NamedHasher interface
+// A non-zero seed is appended to the name, e.g. "murmur3/42".
+func (m Murmur3) Name() string {
+	if m.Seed == 0 {
+		return "murmur3"
+	}
+	return fmt.Sprintf("murmur3/%d", m.Seed)
+}
+
+// Hash implements the Hasher interface
+func (m Murmur3) Hash(data []byte) uint64 {
+	const (
+		c1 = 0x87c37b91114253d5
+		c2 = 0x4cf5ad432745937f
+	)
+
+	h1 := uint64(m.Seed)
+	h2 := uint64(m.Seed)
+	length := len(data)
+
+	// Body
+	nblocks := length / 16
+	for i := 0; i < nblocks; i++ {
+		k1 := binary.LittleEndian.Uint64(data[i*16:])
+		k2 := binary.LittleEndian.Uint64(data[i*16+8:])
+
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+
+		h1 = bits.RotateLeft64(h1, 27)
+		h1 += h2
+		h1 = h1*5 + 0x52dce729
+
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+
+		h2 = bits.RotateLeft64(h2, 31)
+		h2 += h1
+		h2 = h2*5 + 0x38495ab5
+	}
+
+	// Tail
+	tail := data[nblocks*16:]
+	var k1, k2 uint64
+	switch len(tail) & 15 {
+	case 15:
+		k2 ^= uint64(tail[14]) << 48
+		fallthrough
+	case 14:
+		k2 ^= uint64(tail[13]) << 40
+		fallthrough
+	case 13:
+		k2 ^= uint64(tail[12]) << 32
+		fallthrough
+	case 12:
+		k2 ^= uint64(tail[11]) << 24
+		fallthrough
+	case 11:
+		k2 ^= uint64(tail[10]) << 16
+		fallthrough
+	case 10:
+		k2 ^= uint64(tail[9]) << 8
+		fallthrough
+	case 9:
+		k2 ^= uint64(tail[8])
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+		fallthrough
+	case 8:
+		k1 ^= uint64(tail[7]) << 56
+		fallthrough
+	case 7:
+		k1 ^= uint64(tail[6]) << 48
+		fallthrough
+	case 6:
+		k1 ^= uint64(tail[5]) << 40
+		fallthrough
+	case 5:
+		k1 ^= uint64(tail[4]) << 32
+		fallthrough
+	case 4:
+		k1 ^= uint64(tail[3]) << 24
+		fallthrough
+	case 3:
+		k1 ^= uint64(tail[2]) << 16
+		fallthrough
+	case 2:
+		k1 ^= uint64(tail[1]) << 8
+		fallthrough
+	case 1:
+		k1 ^= uint64(tail[0])
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+	}
+
+	// Finalization
+	h1 ^= uint64(length)
+	h2 ^= uint64(length)
+
+	h1 += h2
+	h2 += h1
+
+	h1 = fmix64(h1)
+	h2 = fmix64(h2)
+
+	h1 += h2
+
+	return h1
+}
+
+// fmix64 is the MurmurHash3 64-bit finalizer
+func fmix64(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
+
+// DefaultHasher is the hasher used when none is provided
+var DefaultHasher Hasher = XXHash64{}
+
+// hashers holds the hashers registered with RegisterHasher
+var hashers = struct {
+	sync.RWMutex
+	byName map[string]Hasher
+}{
+	byName: make(map[string]Hasher),
+}
+
+// namedHasher attaches a registered name to a custom hasher
+type namedHasher struct {
+	Hasher
+	name string
+}
+
+// Name implements the NamedHasher interface
+func (n namedHasher) Name() string {
+	return n.name
+}
+
+// RegisterHasher makes a custom hasher available to HasherByName under the given name
+// Pass the returned hasher to WithHasher so serialized rings record its name.
+func RegisterHasher(name string, hasher Hasher) NamedHasher {
+	named := namedHasher{Hasher: hasher, name: name}
+
+	hashers.Lock()
+	defer hashers.Unlock()
+	hashers.byName[name] = named
+
+	return named
+}
+
+// HasherByName returns the built-in or registered hasher with the given name
+func HasherByName(name string) (Hasher, error) {
+	switch name {
+	case XXHash64{}.Name():
+		return XXHash64{}, nil
+	case FNV1a{}.Name():
+		return FNV1a{}, nil
+	case Murmur3{}.Name():
+		return Murmur3{}, nil
+	}
+
+	if seed, ok := strings.CutPrefix(name, "murmur3/"); ok {
+		value, err := strconv.ParseUint(seed, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid murmur3 seed %q: %w", seed, err)
+		}
+		return Murmur3{Seed: uint32(value)}, nil
+	}
+
+	hashers.RLock()
+	defer hashers.RUnlock()
+
+	hasher, ok := hashers.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownHasher, name)
+	}
+
+	return hasher, nil
+}
+
+// hasherName returns the name a hasher is serialized under
+func hasherName(hasher Hasher) (string, error) {
+	named, ok := hasher.(NamedHasher)
+	if !ok {
+		return "", ErrUnknownHasher
+	}
+	return named.Name(), nil
+}
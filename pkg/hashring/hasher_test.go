@@ -0,0 +1,102 @@
+package hashring
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestHasherKnownAnswers(t *testing.T) {
+	tests := []struct {
+		hasher Hasher
+		input  string
+		want   uint64
+	}{
+		{XXHash64{}, "", 0xef46db3751d8e999},
+		{FNV1a{}, "", 0xcbf29ce484222325},
+		{FNV1a{}, "a", 0xaf63dc4c8601ec8c},
+		{Murmur3{}, "", 0},
+		{Murmur3{}, "hello", 0xcbd8a7b341bd9b02},
+		{Murmur3{}, "The quick brown fox jumps over the lazy dog", 0xe34bbc7bbc071b6c},
+	}
+
+	for _, tt := range tests {
+		name := tt.hasher.(NamedHasher).Name()
+		if got := tt.hasher.Hash([]byte(tt.input)); got != tt.want {
+			t.Errorf("%s(%q) = %x, want %x", name, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestMurmur3Seed(t *testing.T) {
+	if (Murmur3{}).Hash([]byte("hello")) == (Murmur3{Seed: 42}).Hash([]byte("hello")) {
+		t.Fatal("seed does not change the hash")
+	}
+
+	hasher, err := HasherByName(Murmur3{Seed: 42}.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasher != (Murmur3{Seed: 42}) {
+		t.Fatalf("HasherByName(murmur3/42) = %#v", hasher)
+	}
+}
+
+func TestHasherDistribution(t *testing.T) {
+	const (
+		nodes = 10
+		keys  = 100000
+	)
+
+	tests := []struct {
+		hasher NamedHasher
+		// maxSpread; the largest accepted ratio between the busiest and the idlest node
+		maxSpread float64
+	}{
+		{XXHash64{}, 1.6},
+		{Murmur3{}, 1.6},
+		// FNV-1a clusters similar keys; the test records how poorly it balances
+		{FNV1a{}, 30},
+	}
+
+	for _, tt := range tests {
+		hasher := tt.hasher
+		t.Run(hasher.Name(), func(t *testing.T) {
+			ring := NewHashRing(100, WithHasher(hasher))
+			for i := 0; i < nodes; i++ {
+				if err := ring.AddNode(&Node{ID: fmt.Sprintf("node-%d", i), Status: NodeStatusActive}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			counts := make(map[string]int, nodes)
+			for i := 0; i < keys; i++ {
+				node, err := ring.GetNode(fmt.Sprintf("key-%d", i))
+				if err != nil {
+					t.Fatal(err)
+				}
+				counts[node.ID]++
+			}
+
+			if len(counts) != nodes {
+				t.Fatalf("keys landed on %d of %d nodes", len(counts), nodes)
+			}
+
+			expected := float64(keys) / nodes
+			minCount, maxCount := keys, 0
+			chiSquare := 0.0
+			for _, count := range counts {
+				minCount = min(minCount, count)
+				maxCount = max(maxCount, count)
+				chiSquare += math.Pow(float64(count)-expected, 2) / expected
+			}
+
+			spread := float64(maxCount) / float64(minCount)
+			t.Logf("min %d, max %d, spread %.2f, chi-square %.0f", minCount, maxCount, spread, chiSquare)
+
+			if spread > tt.maxSpread {
+				t.Errorf("max/min spread %.2f exceeds %.1f", spread, tt.maxSpread)
+			}
+		})
+	}
+}
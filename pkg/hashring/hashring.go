@@ -3,35 +3,62 @@ package hashring
 import (
 	"errors"
 	"fmt"
-	"hash/crc32"
-	"sort"
 	"sync"
+	"sync/atomic"
 )
 
 // HashRing implements a consistent hash ring
+// Lookups never take a lock: they run against an immutable Snapshot published
+// through an atomic pointer. Writers serialise on a mutex, build a new snapshot
+// from the current one and swap it in, so a reader always sees a consistent ring.
 type HashRing struct {
-	nodes        map[string]*Node  // Map of node ID to node
-	virtualNodes map[uint32]string // Map of virtual node hash to node ID
-	sortedHashes []uint32          // Sorted list of virtual node hashes
-	replicaCount int               // Number of virtual nodes per physical node
-	mu           sync.RWMutex      // Protects access to the hash ring
+	snapshot     atomic.Pointer[Snapshot] // Current immutable view of the ring
+	replicaCount int                      // Number of virtual nodes per unit of node weight
+	config       *config                  // Ring settings such as the hash function (writers only)
+	mu           sync.Mutex               // Serialises writers
+	loads        map[string]int           // Number of keys assigned to each node (bounded-load mode)
+	totalLoad    int                      // Sum of all node loads
+	loadMu       sync.RWMutex             // Protects loads and totalLoad
 }
 
 // NewHashRing creates a new consistent hash ring
-func NewHashRing(replicaCount int) *HashRing {
+// The ring hashes with xxHash64 unless another Hasher is given via WithHasher
+func NewHashRing(replicaCount int, opts ...Option) *HashRing {
 	if replicaCount <= 0 {
 		replicaCount = 10 // Default to 10 replicas if invalid count provided
 	}
 
-	return &HashRing{
-		nodes:        make(map[string]*Node),
-		virtualNodes: make(map[uint32]string),
-		sortedHashes: make([]uint32, 0),
+	h := &HashRing{
 		replicaCount: replicaCount,
+		config:       newConfig(opts...),
+		loads:        make(map[string]int),
 	}
+
+	h.snapshot.Store(&Snapshot{
+		nodes:  make(map[string]*Node),
+		hashes: make([]uint64, 0),
+		owners: make([]string, 0),
+		config: h.config,
+	})
+
+	return h
+}
+
+// Snapshot returns the current immutable view of the ring
+// Lookups on the same snapshot always agree with each other, even while
+// nodes are added, removed or change status.
+func (h *HashRing) Snapshot() *Snapshot {
+	return h.snapshot.Load()
+}
+
+// Epoch returns the version of the ring
+// Every change to the ring increments it.
+func (h *HashRing) Epoch() uint64 {
+	return h.snapshot.Load().epoch
 }
 
 // AddNode adds a node to the hash ring
+// The ring stores a copy of the node; later changes to n have no effect.
 func (h *HashRing) AddNode(n *Node) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -44,26 +71,18 @@ func (h *HashRing) AddNode(n *Node) error {
 		return errors.New("node ID cannot be empty")
 	}
 
-	if _, exists := h.nodes[n.ID]; exists {
+	current := h.snapshot.Load()
+	if _, exists := current.nodes[n.ID]; exists {
 		return ErrNodeExists
 	}
 
-	// Add the node to our nodes map
-	h.nodes[n.ID] = n
+	node := *n
+	next := current.withNode(&node)
 
 	// Add virtual nodes
-	for i := 0; i < h.replicaCount; i++ {
-		virtualNodeKey := fmt.Sprintf("%s:%d", n.ID, i)
-		hash := crc32.ChecksumIEEE([]byte(virtualNodeKey))
-		h.virtualNodes[hash] = n.ID
-		h.sortedHashes = append(h.sortedHashes, hash)
-	}
-
-	// Resort hashes
-	sort.Slice(h.sortedHashes, func(i, j int) bool {
-		return h.sortedHashes[i] < h.sortedHashes[j]
-	})
+	next.addVirtualNodes(h.virtualNodeHashes(n.ID, 0, h.virtualNodeCount(n.EffectiveWeight())), n.ID)
 
+	h.snapshot.Store(next)
 	return nil
 }
 
@@ -72,126 +91,175 @@ func (h *HashRing) RemoveNode(nodeID string) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	node, exists := h.nodes[nodeID]
+	current := h.snapshot.Load()
+	node, exists := current.nodes[nodeID]
 	if !exists {
 		return ErrNodeNotFound
 	}
 
-	// Remove the node from our nodes map
-	delete(h.nodes, nodeID)
+	next := current.withoutNode(nodeID)
 
 	// Remove virtual nodes
-	newSortedHashes := make([]uint32, 0, len(h.sortedHashes)-h.replicaCount)
-	for i := 0; i < h.replicaCount; i++ {
-		virtualNodeKey := fmt.Sprintf("%s:%d", node.ID, i)
-		hash := crc32.ChecksumIEEE([]byte(virtualNodeKey))
-		delete(h.virtualNodes, hash)
-
-		// Rebuild the sorted hashes array excluding this node's hashes
-		for _, h := range h.sortedHashes {
-			if h != hash {
-				newSortedHashes = append(newSortedHashes, h)
-			}
-		}
+	next.removeVirtualNodes(h.virtualNodeHashes(nodeID, 0, h.virtualNodeCount(node.EffectiveWeight())), nodeID)
+
+	h.snapshot.Store(next)
+
+	// Forget its load
+	h.loadMu.Lock()
+	h.totalLoad -= h.loads[nodeID]
+	delete(h.loads, nodeID)
+	h.loadMu.Unlock()
+
+	return nil
+}
+
+// UpdateNodeWeight changes the weight of a node
+// Virtual nodes are numbered, so growing a node only adds the new virtual nodes
+// and shrinking it only removes the highest numbered ones. Keys owned by the
+// untouched virtual nodes keep their owner.
+func (h *HashRing) UpdateNodeWeight(nodeID string, weight int) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	node, exists := h.snapshot.Load().nodes[nodeID]
+	if !exists {
+		return ErrNodeNotFound
 	}
 
-	h.sortedHashes = newSortedHashes
+	updated := *node
+	updated.Weight = weight
+	h.replaceNode(&updated)
 	return nil
 }
 
-// GetNode returns the node responsible for the given key
-func (h *HashRing) GetNode(key string) (*Node, error) {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+// UpdateNode replaces the address, status, weight and topology of a node
+// The ring stores a copy of the node; later changes to n have no effect.
+func (h *HashRing) UpdateNode(n *Node) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
-	if len(h.nodes) == 0 {
-		return nil, ErrNoNodes
+	if n == nil {
+		return errors.New("cannot update nil node")
 	}
 
-	// Get the hash of the key
-	hash := crc32.ChecksumIEEE([]byte(key))
+	if _, exists := h.snapshot.Load().nodes[n.ID]; !exists {
+		return ErrNodeNotFound
+	}
 
-	// Find the first virtual node with hash >= key hash
-	idx := sort.Search(len(h.sortedHashes), func(i int) bool {
-		return h.sortedHashes[i] >= hash
-	})
+	updated := *n
+	h.replaceNode(&updated)
+	return nil
+}
 
-	// If we reached the end, wrap around to the first virtual node
-	if idx >= len(h.sortedHashes) {
-		idx = 0
+// replaceNode publishes a snapshot with the node replaced, adding or removing
+// virtual nodes if its weight changed
+// The caller must hold the write lock and ensure the node exists
+func (h *HashRing) replaceNode(updated *Node) {
+	current := h.snapshot.Load()
+	node := current.nodes[updated.ID]
+	next := current.withNode(updated)
+
+	oldCount := h.virtualNodeCount(node.EffectiveWeight())
+	newCount := h.virtualNodeCount(updated.EffectiveWeight())
+
+	if newCount > oldCount {
+		next.addVirtualNodes(h.virtualNodeHashes(updated.ID, oldCount, newCount), updated.ID)
+	} else if newCount < oldCount {
+		next.removeVirtualNodes(h.virtualNodeHashes(updated.ID, newCount, oldCount), updated.ID)
 	}
 
-	// Get the node ID from the virtual node
-	nodeID := h.virtualNodes[h.sortedHashes[idx]]
-	node, exists := h.nodes[nodeID]
+	h.snapshot.Store(next)
+}
+
+// UpdateNodeStatus updates a node's status
+// Nodes previously returned by the ring are not modified; the new status is
+// visible through snapshots taken after the call.
+func (h *HashRing) UpdateNodeStatus(nodeID string, status NodeStatus) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
+	current := h.snapshot.Load()
+	node, exists := current.nodes[nodeID]
 	if !exists {
-		// This should never happen if internal state is consistent
-		return nil, fmt.Errorf("internal error: virtual node points to non-existent node %s", nodeID)
+		return ErrNodeNotFound
 	}
 
-	// If the node is not active, find the next active node
-	if !node.IsAvailable() {
-		return h.getNextAvailableNode(idx)
+	updated := *node
+	updated.SetStatus(status)
+
+	h.snapshot.Store(current.withNode(&updated))
+	return nil
+}
+
+// GetNode returns the node responsible for the given key
+func (h *HashRing) GetNode(key string) (*Node, error) {
+	snapshot := h.snapshot.Load()
+
+	// In bounded-load mode, walk past nodes that are at capacity
+	if snapshot.config.loadFactor > 0 {
+		h.loadMu.RLock()
+		defer h.loadMu.RUnlock()
+		node, err := snapshot.getNodeWithCapacity(key, h.loads, h.totalLoad)
+		if err != nil {
+			return nil, err
+		}
+		return node.clone(), nil
 	}
 
-	return node, nil
+	return snapshot.GetNode(key)
 }
 
-// getNextAvailableNode finds the next available node starting from the given index
-func (h *HashRing) getNextAvailableNode(startIdx int) (*Node, error) {
-	// Create a copy of the sorted hashes to avoid issues with concurrent modification
-	hashes := make([]uint32, len(h.sortedHashes))
-	copy(hashes, h.sortedHashes)
+// GetNodesForKeys resolves a batch of keys against a single snapshot of the ring
+// In bounded-load mode the loads are read once for the whole batch and are
+// not updated as keys are resolved.
+func (h *HashRing) GetNodesForKeys(keys []string) (map[string]*Node, error) {
+	snapshot := h.snapshot.Load()
 
-	// Try each node in the ring
-	for i := 0; i < len(hashes); i++ {
-		idx := (startIdx + i) % len(hashes)
-		nodeID := h.virtualNodes[hashes[idx]]
+	if snapshot.config.loadFactor == 0 {
+		return snapshot.GetNodesForKeys(keys)
+	}
 
-		// Skip virtual nodes pointing to the same physical node we already checked
-		if i > 0 && nodeID == h.virtualNodes[hashes[(startIdx+i-1)%len(hashes)]] {
-			continue
-		}
+	h.loadMu.RLock()
+	defer h.loadMu.RUnlock()
 
-		node, exists := h.nodes[nodeID]
-		if exists && node.IsAvailable() {
-			return node, nil
+	result := make(map[string]*Node, len(keys))
+	for _, key := range keys {
+		node, err := snapshot.getNodeWithCapacity(key, h.loads, h.totalLoad)
+		if err != nil {
+			return nil, err
 		}
+		result[key] = node.clone()
 	}
+	return result, nil
+}
 
-	return nil, errors.New("no active nodes available")
+// GetNodesN walks the ring clockwise from the key and returns up to n distinct available nodes
+// When nodes carry topology, replicas are spread across zones and racks.
+// Bounded loads are not applied to replica sets.
+func (h *HashRing) GetNodesN(key string, n int) ([]*Node, error) {
+	return h.snapshot.Load().GetNodesN(key, n)
 }
 
-// GetNodes returns all nodes in the hash ring
+// GetNodes returns copies of all nodes in the hash ring
 func (h *HashRing) GetNodes() []*Node {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-
-	nodes := make([]*Node, 0, len(h.nodes))
-	for _, node := range h.nodes {
-		nodes = append(nodes, node)
-	}
-	return nodes
+	return h.snapshot.Load().GetNodes()
 }
 
 // GetNodeCount returns the number of nodes in the hash ring
 func (h *HashRing) GetNodeCount() int {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	return len(h.nodes)
+	return h.snapshot.Load().GetNodeCount()
 }
 
-// UpdateNodeStatus updates a node's status
-func (h *HashRing) UpdateNodeStatus(nodeID string, status NodeStatus) error {
-	h.mu.Lock()
-	defer h.mu.Unlock()
+// virtualNodeCount returns the number of virtual nodes for a node of the given weight
+func (h *HashRing) virtualNodeCount(weight int) int {
+	return h.replicaCount * weight
+}
 
-	node, exists := h.nodes[nodeID]
-	if !exists {
-		return ErrNodeNotFound
+// virtualNodeHashes returns the ring positions of virtual nodes [from, to) of a node
+func (h *HashRing) virtualNodeHashes(nodeID string, from, to int) []uint64 {
+	hashes := make([]uint64, 0, to-from)
+	for i := from; i < to; i++ {
+		hashes = append(hashes, h.config.hashString(fmt.Sprintf("%s:%d", nodeID, i)))
 	}
-
-	node.SetStatus(status)
-	return nil
+	return hashes
 }
@@ -0,0 +1,26 @@
+package hashring
+
+import "strings"
+
+// HashTagFunc returns the part of a key that determines its position on the ring
+// Keys that share a hash tag always map to the same node.
+type HashTagFunc func(key string) string
+
+// RedisHashTag extracts hash tags the way Redis Cluster does
+// If the key contains a "{" followed later by a "}" with at least one character
+// in between, only the characters between the first "{" and the first "}" after
+// it are hashed; otherwise the whole key is. "{tenant42}:orders" and
+// "{tenant42}:invoices" both hash as "tenant42", while "{}:orders" hashes whole.
+func RedisHashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return key
+	}
+
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return key
+	}
+
+	return key[start+1 : start+1+end]
+}
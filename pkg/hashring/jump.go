@@ -0,0 +1,309 @@
+package hashring
+
+import (
+	"errors"
+	"sync"
+)
+
+// jumpMaxProbes is the number of re-hashes tried when a key lands on an unavailable node
+const jumpMaxProbes = 16
+
+// JumpRing implements the Ring interface using jump consistent hashing
+// Nodes occupy slots in an ordered table, one slot per unit of weight, and a key
+// is mapped to a slot with the jump hash in O(ln n) time and no memory beyond
+// the table. Lookups are not constant time; use MaglevRing for that.
+// Jump hash only minimises movement when slots are added or removed at the end
+// of the table, so the slots of a removed node are left as tombstones: keys
+// landing on a tombstone are re-hashed like keys of an unavailable node, and
+// only the removed node's keys move. New slots fill tombstones first.
+type JumpRing struct {
+	nodes  map[string]*Node // Map of node ID to node
+	slots  []string         // Ordered slot table of node IDs, "" for tombstones
+	config *config          // Ring settings such as the hash function
+	mu     sync.RWMutex     // Protects access to the ring
+}
+
+// NewJumpRing creates a new jump consistent hash ring
+func NewJumpRing(opts ...Option) *JumpRing {
+	return &JumpRing{
+		nodes:  make(map[string]*Node),
+		slots:  make([]string, 0),
+		config: newConfig(opts...),
+	}
+}
+
+// AddNode adds a node to the end of the slot table
+// The ring stores a copy of the node; later changes to n have no effect.
+func (j *JumpRing) AddNode(n *Node) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if n == nil {
+		return errors.New("cannot add nil node")
+	}
+
+	if n.ID == "" {
+		return errors.New("node ID cannot be empty")
+	}
+
+	if _, exists := j.nodes[n.ID]; exists {
+		return ErrNodeExists
+	}
+
+	node := *n
+	j.nodes[n.ID] = &node
+	j.addSlots(n.ID, n.EffectiveWeight())
+
+	return nil
+}
+
+// RemoveNode removes a node from the slot table
+func (j *JumpRing) RemoveNode(nodeID string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	node, exists := j.nodes[nodeID]
+	if !exists {
+		return ErrNodeNotFound
+	}
+
+	delete(j.nodes, nodeID)
+	j.removeSlots(nodeID, node.EffectiveWeight())
+
+	return nil
+}
+
+// GetNode returns the node responsible for the given key
+// If the key's slot belongs to an unavailable node, the key is re-hashed so
+// that the failed node's keys spread over the remaining nodes.
+func (j *JumpRing) GetNode(key string) (*Node, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	node, err := j.getNode(key)
+	if err != nil {
+		return nil, err
+	}
+	return node.clone(), nil
+}
+
+// GetNodesForKeys returns the node responsible for each of the given keys
+// The whole batch is resolved under a single read lock.
+func (j *JumpRing) GetNodesForKeys(keys []string) (map[string]*Node, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	result := make(map[string]*Node, len(keys))
+	for _, key := range keys {
+		node, err := j.getNode(key)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = node.clone()
+	}
+	return result, nil
+}
+
+// getNode returns the first node in the key's preference order
+// The caller must hold the read lock
+func (j *JumpRing) getNode(key string) (*Node, error) {
+	nodes, err := j.getNodesN(key, 1)
+	if err != nil {
+		return nil, err
+	}
+	return nodes[0], nil
+}
+
+// GetNodesN returns up to n distinct available nodes for the given key in preference order
+// The preference order follows the same re-hash sequence GetNode uses to skip
+// unavailable nodes, then falls back to scanning the slot table. When nodes
+// carry topology, replicas are spread across zones and racks.
+func (j *JumpRing) GetNodesN(key string, n int) ([]*Node, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	nodes, err := j.getNodesN(key, n)
+	if err != nil {
+		return nil, err
+	}
+	return cloneNodes(nodes), nil
+}
+
+// getNodesN implements GetNodesN, returning nodes shared with the ring
+// The caller must hold the read lock
+func (j *JumpRing) getNodesN(key string, n int) ([]*Node, error) {
+	if len(j.nodes) == 0 {
+		return nil, ErrNoNodes
+	}
+
+	if n <= 0 {
+		return []*Node{}, nil
+	}
+
+	hash := j.config.hashKey(key)
+	limit := candidateLimit(j.nodes, n)
+	result := make([]*Node, 0, limit)
+	seen := make(map[string]bool, limit)
+
+	collect := func(slot int) {
+		nodeID := j.slots[slot]
+		if nodeID == "" || seen[nodeID] {
+			return
+		}
+		seen[nodeID] = true
+		if node := j.nodes[nodeID]; node.IsAvailable() {
+			result = append(result, node)
+		}
+	}
+
+	for probe := 0; probe < jumpMaxProbes*limit && len(result) < limit && len(seen) < len(j.nodes); probe++ {
+		collect(jumpHash(hash, len(j.slots)))
+		hash = fmix64(hash + uint64(probe) + 1)
+	}
+
+	// Most of the cluster is down or n is close to the node count; scan the slot table
+	start := jumpHash(hash, len(j.slots))
+	for i := 0; i < len(j.slots) && len(result) < limit && len(seen) < len(j.nodes); i++ {
+		collect((start + i) % len(j.slots))
+	}
+
+	if len(result) == 0 {
+		return nil, ErrNoActiveNodes
+	}
+
+	return selectReplicas(result, n), nil
+}
+
+// GetNodes returns copies of all nodes in the ring
+func (j *JumpRing) GetNodes() []*Node {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	nodes := make([]*Node, 0, len(j.nodes))
+	for _, node := range j.nodes {
+		copied := *node
+		nodes = append(nodes, &copied)
+	}
+	return nodes
+}
+
+// GetNodeCount returns the number of nodes in the ring
+func (j *JumpRing) GetNodeCount() int {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return len(j.nodes)
+}
+
+// UpdateNodeStatus updates a node's status
+func (j *JumpRing) UpdateNodeStatus(nodeID string, status NodeStatus) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	node, exists := j.nodes[nodeID]
+	if !exists {
+		return ErrNodeNotFound
+	}
+
+	// Replace rather than modify the node, which callers may still hold
+	updated := *node
+	updated.SetStatus(status)
+	j.nodes[nodeID] = &updated
+	return nil
+}
+
+// UpdateNodeWeight changes the number of slots a node occupies
+func (j *JumpRing) UpdateNodeWeight(nodeID string, weight int) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	node, exists := j.nodes[nodeID]
+	if !exists {
+		return ErrNodeNotFound
+	}
+
+	updated := *node
+	updated.Weight = weight
+	j.replaceNode(&updated)
+	return nil
+}
+
+// UpdateNode replaces the address, status, weight and topology of a node
+// The ring stores a copy of the node; later changes to n have no effect.
+func (j *JumpRing) UpdateNode(n *Node) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if n == nil {
+		return errors.New("cannot update nil node")
+	}
+
+	if _, exists := j.nodes[n.ID]; !exists {
+		return ErrNodeNotFound
+	}
+
+	updated := *n
+	j.replaceNode(&updated)
+	return nil
+}
+
+// replaceNode replaces a node, adding or removing slots if its weight changed
+// The caller must hold the write lock and ensure the node exists
+func (j *JumpRing) replaceNode(updated *Node) {
+	node := j.nodes[updated.ID]
+	j.nodes[updated.ID] = updated
+
+	oldCount := node.EffectiveWeight()
+	newCount := updated.EffectiveWeight()
+
+	if newCount > oldCount {
+		j.addSlots(updated.ID, newCount-oldCount)
+	} else if newCount < oldCount {
+		j.removeSlots(updated.ID, oldCount-newCount)
+	}
+}
+
+// addSlots gives a node count slots, filling tombstones before growing the table
+// Keys only move to the node: keys of a filled tombstone were re-hashed away
+// from it, and jump hash moves keys to appended slots only.
+// The caller must hold the write lock
+func (j *JumpRing) addSlots(nodeID string, count int) {
+	for i := 0; i < len(j.slots) && count > 0; i++ {
+		if j.slots[i] == "" {
+			j.slots[i] = nodeID
+			count--
+		}
+	}
+
+	for ; count > 0; count-- {
+		j.slots = append(j.slots, nodeID)
+	}
+}
+
+// removeSlots turns up to count slots owned by a node into tombstones, starting from the end of the table
+// Tombstones at the end of the table are dropped; jump hash keeps the slot of
+// every key below the new table size, so only keys of tombstones move.
+// The caller must hold the write lock
+func (j *JumpRing) removeSlots(nodeID string, count int) {
+	for i := len(j.slots) - 1; i >= 0 && count > 0; i-- {
+		if j.slots[i] == nodeID {
+			j.slots[i] = ""
+			count--
+		}
+	}
+
+	for len(j.slots) > 0 && j.slots[len(j.slots)-1] == "" {
+		j.slots = j.slots[:len(j.slots)-1]
+	}
+}
+
+// jumpHash maps a 64-bit key onto one of numBuckets buckets
+// See Lamping & Veach, "A Fast, Minimal Memory, Consistent Hash Algorithm"
+func jumpHash(key uint64, numBuckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(numBuckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
@@ -0,0 +1,87 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+)
+
+// jumpOwners returns the owner of each of count keys
+func jumpOwners(t *testing.T, ring *JumpRing, count int) []string {
+	t.Helper()
+
+	owners := make([]string, count)
+	for i := range owners {
+		node, err := ring.GetNode(fmt.Sprintf("key-%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		owners[i] = node.ID
+	}
+	return owners
+}
+
+func TestJumpRingRemovalOnlyMovesRemovedKeys(t *testing.T) {
+	ring := NewJumpRing()
+	for i := 0; i < 8; i++ {
+		if err := ring.AddNode(&Node{ID: fmt.Sprintf("node-%d", i), Status: NodeStatusActive, Weight: 1 + i%3}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before := jumpOwners(t, ring, 20000)
+
+	if err := ring.RemoveNode("node-3"); err != nil {
+		t.Fatal(err)
+	}
+
+	after := jumpOwners(t, ring, 20000)
+	for i := range before {
+		if before[i] != "node-3" && before[i] != after[i] {
+			t.Fatalf("key-%d moved from %s to %s", i, before[i], after[i])
+		}
+		if after[i] == "node-3" {
+			t.Fatalf("key-%d still maps to the removed node", i)
+		}
+	}
+
+	// A new node fills the tombstones; keys only move onto it
+	if err := ring.AddNode(&Node{ID: "node-8", Status: NodeStatusActive, Weight: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	added := jumpOwners(t, ring, 20000)
+	moved := 0
+	for i := range after {
+		if after[i] != added[i] {
+			if added[i] != "node-8" {
+				t.Fatalf("key-%d moved from %s to %s", i, after[i], added[i])
+			}
+			moved++
+		}
+	}
+	if moved == 0 {
+		t.Fatal("no keys moved to the new node")
+	}
+}
+
+func TestJumpRingWeightChangeOnlyMovesNodeKeys(t *testing.T) {
+	ring := NewJumpRing()
+	for i := 0; i < 5; i++ {
+		if err := ring.AddNode(&Node{ID: fmt.Sprintf("node-%d", i), Status: NodeStatusActive, Weight: 3}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before := jumpOwners(t, ring, 20000)
+
+	if err := ring.UpdateNodeWeight("node-1", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	after := jumpOwners(t, ring, 20000)
+	for i := range before {
+		if before[i] != "node-1" && before[i] != after[i] {
+			t.Fatalf("key-%d moved from %s to %s", i, before[i], after[i])
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package hashring
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// DefaultMaglevTableSize is the lookup table size used when none is given
+// It must be prime and should be much larger than the number of nodes.
+const DefaultMaglevTableSize = 65537
+
+// MaglevRing implements the Ring interface using Maglev hashing
+// Every available node fills slots of a fixed-size lookup table following its own
+// permutation, in proportion to its weight. A lookup is a single table index, and
+// rebuilding the table after a change moves close to the minimal number of slots.
+type MaglevRing struct {
+	nodes     map[string]*Node // Map of node ID to node
+	table     []string         // Lookup table of node IDs; empty if no node is available
+	available int              // Number of distinct nodes in the lookup table
+	tableSize uint64           // Size of the lookup table (prime)
+	config    *config          // Ring settings such as the hash function
+	mu        sync.RWMutex     // Protects access to the ring
+}
+
+// NewMaglevRing creates a new Maglev hash ring with the given lookup table size
+// A tableSize of 0 selects DefaultMaglevTableSize. Other sizes must be prime.
+func NewMaglevRing(tableSize int, opts ...Option) (*MaglevRing, error) {
+	if tableSize == 0 {
+		tableSize = DefaultMaglevTableSize
+	}
+
+	if tableSize < 2 || !isPrime(uint64(tableSize)) {
+		return nil, ErrInvalidTableSize
+	}
+
+	return &MaglevRing{
+		nodes:     make(map[string]*Node),
+		table:     make([]string, 0),
+		tableSize: uint64(tableSize),
+		config:    newConfig(opts...),
+	}, nil
+}
+
+// AddNode adds a node to the ring and rebuilds the lookup table
+// The ring stores a copy of the node; later changes to n have no effect.
+func (m *MaglevRing) AddNode(n *Node) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if n == nil {
+		return errors.New("cannot add nil node")
+	}
+
+	if n.ID == "" {
+		return errors.New("node ID cannot be empty")
+	}
+
+	if _, exists := m.nodes[n.ID]; exists {
+		return ErrNodeExists
+	}
+
+	node := *n
+	m.nodes[n.ID] = &node
+	m.populate()
+
+	return nil
+}
+
+// RemoveNode removes a node from the ring and rebuilds the lookup table
+func (m *MaglevRing) RemoveNode(nodeID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.nodes[nodeID]; !exists {
+		return ErrNodeNotFound
+	}
+
+	delete(m.nodes, nodeID)
+	m.populate()
+
+	return nil
+}
+
+// GetNode returns the node responsible for the given key
+func (m *MaglevRing) GetNode(key string) (*Node, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	node, err := m.getNode(key)
+	if err != nil {
+		return nil, err
+	}
+	return node.clone(), nil
+}
+
+// GetNodesForKeys returns the node responsible for each of the given keys
+// The whole batch is resolved under a single read lock.
+func (m *MaglevRing) GetNodesForKeys(keys []string) (map[string]*Node, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make(map[string]*Node, len(keys))
+	for _, key := range keys {
+		node, err := m.getNode(key)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = node.clone()
+	}
+	return result, nil
+}
+
+// getNode looks the key up in the table
+// The caller must hold the read lock
+func (m *MaglevRing) getNode(key string) (*Node, error) {
+	if len(m.nodes) == 0 {
+		return nil, ErrNoNodes
+	}
+
+	if len(m.table) == 0 {
+		return nil, ErrNoActiveNodes
+	}
+
+	hash := m.config.hashKey(key)
+	return m.nodes[m.table[hash%m.tableSize]], nil
+}
+
+// GetNodesN returns up to n distinct available nodes for the given key in preference order
+// The lookup table is walked forward from the key's slot. When nodes carry
+// topology, replicas are spread across zones and racks.
+func (m *MaglevRing) GetNodesN(key string, n int) ([]*Node, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.nodes) == 0 {
+		return nil, ErrNoNodes
+	}
+
+	if len(m.table) == 0 {
+		return nil, ErrNoActiveNodes
+	}
+
+	if n <= 0 {
+		return []*Node{}, nil
+	}
+
+	start := m.config.hashKey(key) % m.tableSize
+	limit := candidateLimit(m.nodes, n)
+	result := make([]*Node, 0, limit)
+	seen := make(map[string]bool, limit)
+	for i := uint64(0); i < m.tableSize && len(result) < limit && len(seen) < m.available; i++ {
+		nodeID := m.table[(start+i)%m.tableSize]
+		if seen[nodeID] {
+			continue
+		}
+		seen[nodeID] = true
+		result = append(result, m.nodes[nodeID])
+	}
+
+	return cloneNodes(selectReplicas(result, n)), nil
+}
+
+// GetNodes returns copies of all nodes in the ring
+func (m *MaglevRing) GetNodes() []*Node {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	nodes := make([]*Node, 0, len(m.nodes))
+	for _, node := range m.nodes {
+		copied := *node
+		nodes = append(nodes, &copied)
+	}
+	return nodes
+}
+
+// GetNodeCount returns the number of nodes in the ring
+func (m *MaglevRing) GetNodeCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.nodes)
+}
+
+// UpdateNodeStatus updates a node's status
+// The lookup table only holds available nodes, so it is rebuilt when
+// availability changes.
+func (m *MaglevRing) UpdateNodeStatus(nodeID string, status NodeStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node, exists := m.nodes[nodeID]
+	if !exists {
+		return ErrNodeNotFound
+	}
+
+	// Replace rather than modify the node, which callers may still hold
+	updated := *node
+	updated.SetStatus(status)
+	m.nodes[nodeID] = &updated
+
+	if updated.IsAvailable() != node.IsAvailable() {
+		m.populate()
+	}
+
+	return nil
+}
+
+// UpdateNodeWeight updates a node's weight and rebuilds the lookup table
+func (m *MaglevRing) UpdateNodeWeight(nodeID string, weight int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node, exists := m.nodes[nodeID]
+	if !exists {
+		return ErrNodeNotFound
+	}
+
+	updated := *node
+	updated.Weight = weight
+	m.nodes[nodeID] = &updated
+	m.populate()
+
+	return nil
+}
+
+// UpdateNode replaces the address, status, weight and topology of a node and rebuilds the lookup table
+// The ring stores a copy of the node; later changes to n have no effect.
+func (m *MaglevRing) UpdateNode(n *Node) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if n == nil {
+		return errors.New("cannot update nil node")
+	}
+
+	if _, exists := m.nodes[n.ID]; !exists {
+		return ErrNodeNotFound
+	}
+
+	updated := *n
+	m.nodes[n.ID] = &updated
+	m.populate()
+
+	return nil
+}
+
+// populate rebuilds the lookup table from the available nodes
+// Nodes take turns claiming the next free slot of their permutation; a node
+// with weight w takes w turns per round.
+// The caller must hold the write lock
+func (m *MaglevRing) populate() {
+	// Sort the candidates so every process builds the same table
+	ids := make([]string, 0, len(m.nodes))
+	for id, node := range m.nodes {
+		if node.IsAvailable() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	m.available = len(ids)
+
+	if len(ids) == 0 {
+		m.table = make([]string, 0)
+		return
+	}
+
+	offsets := make([]uint64, len(ids))
+	skips := make([]uint64, len(ids))
+	next := make([]uint64, len(ids))
+	for i, id := range ids {
+		offsets[i] = m.config.hashString(id+"#offset") % m.tableSize
+		skips[i] = m.config.hashString(id+"#skip")%(m.tableSize-1) + 1
+	}
+
+	table := make([]string, m.tableSize)
+	filled := uint64(0)
+	for filled < m.tableSize {
+		for i, id := range ids {
+			for turn := 0; turn < m.nodes[id].EffectiveWeight() && filled < m.tableSize; turn++ {
+				// Find the next free slot in this node's permutation
+				slot := (offsets[i] + next[i]*skips[i]) % m.tableSize
+				for table[slot] != "" {
+					next[i]++
+					slot = (offsets[i] + next[i]*skips[i]) % m.tableSize
+				}
+
+				table[slot] = id
+				next[i]++
+				filled++
+			}
+		}
+	}
+
+	m.table = table
+}
+
+// isPrime reports whether n is prime
+func isPrime(n uint64) bool {
+	if n < 2 {
+		return false
+	}
+	for d := uint64(2); d*d <= n; d++ {
+		if n%d == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -17,16 +17,24 @@ const (
 // Node represents a physical node in the system
 type Node struct {
 	// ID is the unique identifier for this node
-	ID string
+	ID string `json:"id"`
 
 	// Address is the network address of the node
-	Address string
+	Address string `json:"address"`
 
 	// Status indicates the current operational status
-	Status NodeStatus
+	Status NodeStatus `json:"status"`
 
 	// LastHeartbeat is the Unix timestamp of the last heartbeat received
-	LastHeartbeat int64
+	LastHeartbeat int64 `json:"last_heartbeat,omitempty"`
+
+	// Weight is the relative capacity of the node
+	// A node with weight 4 receives four times the virtual nodes of a node with weight 1.
+	// Zero or negative weights are treated as 1.
+	Weight int `json:"weight,omitempty"`
+
+	// Topology is the region, zone and rack the node runs in
+	Topology Topology `json:"topology,omitzero"`
 }
 
 // NewNode creates a new node with the given ID and address
@@ -38,11 +46,46 @@ func NewNode(id, address string) *Node {
 	}
 }
 
-// IsAvailable returns true if the node is available to handle requests
+// clone returns a copy of the node
+func (n *Node) clone() *Node {
+	copied := *n
+	return &copied
+}
+
+// cloneNodes returns copies of the given nodes
+func cloneNodes(nodes []*Node) []*Node {
+	copies := make([]*Node, len(nodes))
+	for i, node := range nodes {
+		copies[i] = node.clone()
+	}
+	return copies
+}
+
+// IsAvailable returns true if the node is available to take new keys
+// Draining nodes are not available: they keep serving the keys already mapped
+// to them but receive no new ones.
 func (n *Node) IsAvailable() bool {
 	return n.Status == NodeStatusActive
 }
 
+// IsDraining returns true if the node is being drained before removal
+func (n *Node) IsDraining() bool {
+	return n.Status == NodeStatusDraining
+}
+
+// IsServing returns true if the node still serves the keys mapped to it
+func (n *Node) IsServing() bool {
+	return n.Status == NodeStatusActive || n.Status == NodeStatusDraining
+}
+
+// EffectiveWeight returns the node's weight, defaulting to 1 when unset
+func (n *Node) EffectiveWeight() int {
+	if n.Weight <= 0 {
+		return 1
+	}
+	return n.Weight
+}
+
 // SetStatus updates the node's status
 func (n *Node) SetStatus(status NodeStatus) {
 	n.Status = status
@@ -0,0 +1,77 @@
+package hashring
+
+// Option configures a ring at construction time
+type Option func(*config)
+
+// config holds the settings shared by ring implementations
+type config struct {
+	// hasher; the hash function used to place nodes and keys on the ring
+	hasher Hasher
+	// loadFactor; the capacity factor c for consistent hashing with bounded loads
+	// 0 disables bounded loads
+	loadFactor float64
+	// hashTag; extracts the part of a key that is hashed, nil hashes the whole key
+	hashTag HashTagFunc
+}
+
+// newConfig builds a config from the given options, applying defaults
+func newConfig(opts ...Option) *config {
+	cfg := &config{
+		hasher: DefaultHasher,
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(cfg)
+		}
+	}
+
+	return cfg
+}
+
+// WithHasher sets the hash function used by the ring
+// A nil hasher leaves the default (xxHash64) in place
+func WithHasher(hasher Hasher) Option {
+	return func(c *config) {
+		if hasher != nil {
+			c.hasher = hasher
+		}
+	}
+}
+
+// WithBoundedLoad enables consistent hashing with bounded loads
+// No node is assigned a key that would take its load above ceil(c * average load),
+// where the average is weighted by node weight. Factors below 1 are ignored.
+func WithBoundedLoad(c float64) Option {
+	return func(cfg *config) {
+		if c >= 1 {
+			cfg.loadFactor = c
+		}
+	}
+}
+
+// WithHashTags enables hash tags: only the part of a key returned by the
+// extractor is hashed, so related keys land on the same node
+// A nil extractor selects RedisHashTag.
+func WithHashTags(extractor HashTagFunc) Option {
+	return func(c *config) {
+		if extractor == nil {
+			extractor = RedisHashTag
+		}
+		c.hashTag = extractor
+	}
+}
+
+// hashKey returns the ring position of the given key, honouring hash tags
+func (c *config) hashKey(key string) uint64 {
+	if c.hashTag != nil {
+		key = c.hashTag(key)
+	}
+	return c.hasher.Hash([]byte(key))
+}
+
+// hashString returns the ring position of a string used for node placement
+// Hash tags never apply to node placement.
+func (c *config) hashString(s string) uint64 {
+	return c.hasher.Hash([]byte(s))
+}
@@ -0,0 +1,281 @@
+package hashring
+
+import (
+	"math"
+	"sort"
+)
+
+// TokenRange is a contiguous range of the hash space; Start and End are inclusive
+type TokenRange struct {
+	// Start is the first hash in the range
+	Start uint64
+
+	// End is the last hash in the range
+	End uint64
+}
+
+// NodeOwnership describes the part of the hash space a node owns
+type NodeOwnership struct {
+	// NodeID is the ID of the node
+	NodeID string
+
+	// Share is the fraction of the hash space the node owns, between 0 and 1
+	Share float64
+
+	// Expected is the share the node would own with perfect balance, given its weight
+	Expected float64
+
+	// Ranges are the token ranges the node owns, sorted by Start
+	// Rings without contiguous ownership (rendezvous, Maglev) leave this empty.
+	Ranges []TokenRange
+}
+
+// BalanceStats summarises how evenly the hash space is spread
+// The statistics are computed over each node's Share divided by its Expected
+// share, so 1 means a node owns exactly its fair share whatever its weight.
+type BalanceStats struct {
+	// Mean is the average relative share
+	Mean float64
+
+	// StdDev is the standard deviation of the relative shares
+	StdDev float64
+
+	// Min is the smallest relative share
+	Min float64
+
+	// Max is the largest relative share
+	Max float64
+
+	// MaxMinRatio is Max divided by Min; +Inf if some node owns nothing
+	MaxMinRatio float64
+}
+
+// OwnershipReport describes how the hash space is split between available nodes
+type OwnershipReport struct {
+	// Nodes lists every available node, sorted by ID
+	Nodes []NodeOwnership
+
+	// Balance summarises the spread of the shares
+	Balance BalanceStats
+}
+
+// newOwnershipReport builds a report from per-node shares, ranges and weights
+// Only the nodes present in weights are reported.
+func newOwnershipReport(shares map[string]float64, ranges map[string][]TokenRange, weights map[string]int) *OwnershipReport {
+	report := &OwnershipReport{
+		Nodes: make([]NodeOwnership, 0, len(weights)),
+	}
+
+	totalWeight := 0
+	for _, weight := range weights {
+		totalWeight += weight
+	}
+
+	if totalWeight == 0 {
+		return report
+	}
+
+	for nodeID, weight := range weights {
+		report.Nodes = append(report.Nodes, NodeOwnership{
+			NodeID:   nodeID,
+			Share:    shares[nodeID],
+			Expected: float64(weight) / float64(totalWeight),
+			Ranges:   ranges[nodeID],
+		})
+	}
+
+	sort.Slice(report.Nodes, func(i, j int) bool {
+		return report.Nodes[i].NodeID < report.Nodes[j].NodeID
+	})
+
+	relative := make([]float64, len(report.Nodes))
+	for i, node := range report.Nodes {
+		relative[i] = node.Share / node.Expected
+	}
+
+	report.Balance = newBalanceStats(relative)
+	return report
+}
+
+// newBalanceStats computes summary statistics over relative shares
+func newBalanceStats(values []float64) BalanceStats {
+	stats := BalanceStats{
+		Min: math.Inf(1),
+		Max: math.Inf(-1),
+	}
+
+	for _, v := range values {
+		stats.Mean += v
+		stats.Min = math.Min(stats.Min, v)
+		stats.Max = math.Max(stats.Max, v)
+	}
+	stats.Mean /= float64(len(values))
+
+	for _, v := range values {
+		stats.StdDev += (v - stats.Mean) * (v - stats.Mean)
+	}
+	stats.StdDev = math.Sqrt(stats.StdDev / float64(len(values)))
+
+	if stats.Min > 0 {
+		stats.MaxMinRatio = stats.Max / stats.Min
+	} else {
+		stats.MaxMinRatio = math.Inf(1)
+	}
+
+	return stats
+}
+
+// availableWeights returns the weights of the available nodes
+func availableWeights(nodes map[string]*Node) map[string]int {
+	weights := make(map[string]int, len(nodes))
+	for id, node := range nodes {
+		if node.IsAvailable() {
+			weights[id] = node.EffectiveWeight()
+		}
+	}
+	return weights
+}
+
+// rangeSize returns the fraction of the 64-bit hash space covered by a range
+func rangeSize(r TokenRange) float64 {
+	return (float64(r.End-r.Start) + 1) / math.Exp2(64)
+}
+
+// Ownership reports the share and token ranges each available node owns
+func (s *Snapshot) Ownership() *OwnershipReport {
+	shares := make(map[string]float64)
+	ranges := make(map[string][]TokenRange)
+
+	if len(s.hashes) > 0 {
+		owners := s.effectiveOwners()
+
+		add := func(nodeID string, r TokenRange) {
+			if nodeID == "" {
+				return
+			}
+			shares[nodeID] += rangeSize(r)
+
+			// Merge with the previous range when contiguous
+			if n := len(ranges[nodeID]); n > 0 && ranges[nodeID][n-1].End+1 == r.Start {
+				ranges[nodeID][n-1].End = r.End
+				return
+			}
+			ranges[nodeID] = append(ranges[nodeID], r)
+		}
+
+		// [0, first hash] belongs to the first virtual node
+		add(owners[0], TokenRange{Start: 0, End: s.hashes[0]})
+
+		for i := 1; i < len(s.hashes); i++ {
+			add(owners[i], TokenRange{Start: s.hashes[i-1] + 1, End: s.hashes[i]})
+		}
+
+		// (last hash, max] wraps around to the first virtual node
+		if last := s.hashes[len(s.hashes)-1]; last < math.MaxUint64 {
+			add(owners[0], TokenRange{Start: last + 1, End: math.MaxUint64})
+		}
+	}
+
+	return newOwnershipReport(shares, ranges, availableWeights(s.nodes))
+}
+
+// Ownership reports the share and token ranges each available node owns
+func (h *HashRing) Ownership() *OwnershipReport {
+	return h.snapshot.Load().Ownership()
+}
+
+// rendezvousOwnershipSamples is the number of evenly spaced key hashes RendezvousRing.Ownership scores
+// The measured shares are within about half a percentage point of the true shares.
+const rendezvousOwnershipSamples = 1 << 16
+
+// Ownership reports the measured share of each available node
+// Rendezvous hashing has no token ranges, so shares are measured by scoring
+// evenly spaced points of the key hash space. This reflects the actual balance of
+// the node seeds rather than the weights alone, and costs one score per sample
+// and available node.
+func (r *RendezvousRing) Ownership() *OwnershipReport {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	nodes := make(map[string]*Node, len(r.nodes))
+	available := make([]*rendezvousNode, 0, len(r.nodes))
+	for id, n := range r.nodes {
+		nodes[id] = n.node
+		if n.node.IsAvailable() {
+			available = append(available, n)
+		}
+	}
+
+	wins := make(map[string]int, len(available))
+	if len(available) > 0 {
+		step := uint64(math.MaxUint64/rendezvousOwnershipSamples + 1)
+		for i := uint64(0); i < rendezvousOwnershipSamples; i++ {
+			hash := i*step + step/2
+
+			var best *rendezvousNode
+			bestScore := math.Inf(-1)
+			for _, candidate := range available {
+				score := rendezvousScore(hash, candidate)
+				// Same tie-break as getNode
+				if best == nil || score > bestScore || (score == bestScore && candidate.node.ID < best.node.ID) {
+					best = candidate
+					bestScore = score
+				}
+			}
+			wins[best.node.ID]++
+		}
+	}
+
+	shares := make(map[string]float64, len(wins))
+	for id, count := range wins {
+		shares[id] = float64(count) / rendezvousOwnershipSamples
+	}
+
+	return newOwnershipReport(shares, nil, availableWeights(nodes))
+}
+
+// Ownership reports the share of each available node
+// Shares follow the slot table: keys of slots owned by unavailable nodes are
+// re-hashed, so they spread over the available slots in proportion. Jump hash
+// maps keys to slots rather than to token ranges, so no ranges are reported.
+func (j *JumpRing) Ownership() *OwnershipReport {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	weights := availableWeights(j.nodes)
+
+	availableSlots := 0
+	slots := make(map[string]int, len(weights))
+	for _, nodeID := range j.slots {
+		if _, ok := weights[nodeID]; ok {
+			slots[nodeID]++
+			availableSlots++
+		}
+	}
+
+	shares := make(map[string]float64, len(slots))
+	for id, count := range slots {
+		shares[id] = float64(count) / float64(availableSlots)
+	}
+
+	return newOwnershipReport(shares, nil, weights)
+}
+
+// Ownership reports the share of the lookup table each available node owns
+// Maglev maps keys to table slots by modulo, so no token ranges are reported.
+func (m *MaglevRing) Ownership() *OwnershipReport {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	slots := make(map[string]int, m.available)
+	for _, nodeID := range m.table {
+		slots[nodeID]++
+	}
+
+	shares := make(map[string]float64, len(slots))
+	for id, count := range slots {
+		shares[id] = float64(count) / float64(m.tableSize)
+	}
+
+	return newOwnershipReport(shares, nil, availableWeights(m.nodes))
+}
@@ -0,0 +1,62 @@
+package hashring
+
+import "strconv"
+
+// Partitioner splits the keyspace into a fixed number of partitions
+// Keys hash to partitions, and partitions are placed on a ring like ordinary
+// keys, so a membership change moves whole partitions and only those whose
+// owner changed.
+type Partitioner struct {
+	count  int     // Number of partitions
+	config *config // Hash function and hash tags used for keys
+}
+
+// NewPartitioner creates a partitioner with the given number of partitions
+// Keys are hashed with the ring options given, so hash tags keep related keys
+// in the same partition.
+func NewPartitioner(count int, opts ...Option) (*Partitioner, error) {
+	if count <= 0 {
+		return nil, ErrInvalidPartitionCount
+	}
+
+	return &Partitioner{
+		count:  count,
+		config: newConfig(opts...),
+	}, nil
+}
+
+// Count returns the number of partitions
+func (p *Partitioner) Count() int {
+	return p.count
+}
+
+// Partition returns the partition the given key belongs to, in [0, Count())
+func (p *Partitioner) Partition(key string) int {
+	return int(p.config.hashKey(key) % uint64(p.count))
+}
+
+// PartitionKey returns the key a partition is placed on a ring with
+func PartitionKey(partition int) string {
+	return "partition:" + strconv.Itoa(partition)
+}
+
+// Assign returns the owner of every partition on the given ring
+// owners[i] is the ID of the node owning partition i.
+func (p *Partitioner) Assign(ring Ring) ([]string, error) {
+	keys := make([]string, p.count)
+	for i := range keys {
+		keys[i] = PartitionKey(i)
+	}
+
+	nodes, err := ring.GetNodesForKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make([]string, p.count)
+	for i, key := range keys {
+		owners[i] = nodes[key].ID
+	}
+
+	return owners, nil
+}
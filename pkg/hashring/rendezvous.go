@@ -0,0 +1,266 @@
+package hashring
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+)
+
+// RendezvousRing implements the Ring interface using rendezvous
+// (highest random weight) hashing
+// Every node scores every key and the highest scoring available node owns the
+// key. There are no virtual nodes; balance comes from the hash function, and
+// removing a node only moves the keys it owned.
+type RendezvousRing struct {
+	nodes  map[string]*rendezvousNode // Map of node ID to node
+	config *config                    // Ring settings such as the hash function
+	mu     sync.RWMutex               // Protects access to the ring
+}
+
+// rendezvousNode is a node together with its precomputed seed
+type rendezvousNode struct {
+	node *Node
+	seed uint64 // Hash of the node ID, mixed with key hashes to score keys
+}
+
+// NewRendezvousRing creates a new rendezvous hash ring
+func NewRendezvousRing(opts ...Option) *RendezvousRing {
+	return &RendezvousRing{
+		nodes:  make(map[string]*rendezvousNode),
+		config: newConfig(opts...),
+	}
+}
+
+// AddNode adds a node to the ring
+// The ring stores a copy of the node; later changes to n have no effect.
+func (r *RendezvousRing) AddNode(n *Node) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if n == nil {
+		return errors.New("cannot add nil node")
+	}
+
+	if n.ID == "" {
+		return errors.New("node ID cannot be empty")
+	}
+
+	if _, exists := r.nodes[n.ID]; exists {
+		return ErrNodeExists
+	}
+
+	node := *n
+	r.nodes[n.ID] = &rendezvousNode{
+		node: &node,
+		seed: r.config.hashString(n.ID),
+	}
+
+	return nil
+}
+
+// RemoveNode removes a node from the ring
+func (r *RendezvousRing) RemoveNode(nodeID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.nodes[nodeID]; !exists {
+		return ErrNodeNotFound
+	}
+
+	delete(r.nodes, nodeID)
+	return nil
+}
+
+// GetNode returns the available node with the highest score for the given key
+func (r *RendezvousRing) GetNode(key string) (*Node, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	node, err := r.getNode(key)
+	if err != nil {
+		return nil, err
+	}
+	return node.clone(), nil
+}
+
+// GetNodesForKeys returns the node responsible for each of the given keys
+// The whole batch is resolved under a single read lock.
+func (r *RendezvousRing) GetNodesForKeys(keys []string) (map[string]*Node, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make(map[string]*Node, len(keys))
+	for _, key := range keys {
+		node, err := r.getNode(key)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = node.clone()
+	}
+	return result, nil
+}
+
+// getNode returns the available node with the highest score for the given key
+// The caller must hold the read lock
+func (r *RendezvousRing) getNode(key string) (*Node, error) {
+	if len(r.nodes) == 0 {
+		return nil, ErrNoNodes
+	}
+
+	hash := r.config.hashKey(key)
+
+	var best *rendezvousNode
+	bestScore := math.Inf(-1)
+	for _, candidate := range r.nodes {
+		if !candidate.node.IsAvailable() {
+			continue
+		}
+
+		score := rendezvousScore(hash, candidate)
+		// Break ties on the node ID so the result does not depend on map order
+		if best == nil || score > bestScore || (score == bestScore && candidate.node.ID < best.node.ID) {
+			best = candidate
+			bestScore = score
+		}
+	}
+
+	if best == nil {
+		return nil, ErrNoActiveNodes
+	}
+
+	return best.node, nil
+}
+
+// GetNodesN returns the n available nodes with the highest scores for the given key
+// When nodes carry topology, replicas are spread across zones and racks.
+func (r *RendezvousRing) GetNodesN(key string, n int) ([]*Node, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.nodes) == 0 {
+		return nil, ErrNoNodes
+	}
+
+	if n <= 0 {
+		return []*Node{}, nil
+	}
+
+	hash := r.config.hashKey(key)
+
+	type scored struct {
+		node  *Node
+		score float64
+	}
+
+	candidates := make([]scored, 0, len(r.nodes))
+	for _, candidate := range r.nodes {
+		if candidate.node.IsAvailable() {
+			candidates = append(candidates, scored{
+				node:  candidate.node,
+				score: rendezvousScore(hash, candidate),
+			})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, ErrNoActiveNodes
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].node.ID < candidates[j].node.ID
+	})
+
+	result := make([]*Node, len(candidates))
+	for i, candidate := range candidates {
+		result[i] = candidate.node
+	}
+
+	return cloneNodes(selectReplicas(result, n)), nil
+}
+
+// GetNodes returns copies of all nodes in the ring
+func (r *RendezvousRing) GetNodes() []*Node {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	nodes := make([]*Node, 0, len(r.nodes))
+	for _, n := range r.nodes {
+		copied := *n.node
+		nodes = append(nodes, &copied)
+	}
+	return nodes
+}
+
+// GetNodeCount returns the number of nodes in the ring
+func (r *RendezvousRing) GetNodeCount() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.nodes)
+}
+
+// UpdateNodeStatus updates a node's status
+func (r *RendezvousRing) UpdateNodeStatus(nodeID string, status NodeStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, exists := r.nodes[nodeID]
+	if !exists {
+		return ErrNodeNotFound
+	}
+
+	// Replace rather than modify the node, which callers may still hold
+	updated := *n.node
+	updated.SetStatus(status)
+	r.nodes[nodeID] = &rendezvousNode{node: &updated, seed: n.seed}
+	return nil
+}
+
+// UpdateNodeWeight updates a node's weight
+// Only keys whose highest score moves to or from this node change owner.
+func (r *RendezvousRing) UpdateNodeWeight(nodeID string, weight int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, exists := r.nodes[nodeID]
+	if !exists {
+		return ErrNodeNotFound
+	}
+
+	updated := *n.node
+	updated.Weight = weight
+	r.nodes[nodeID] = &rendezvousNode{node: &updated, seed: n.seed}
+	return nil
+}
+
+// UpdateNode replaces the address, status, weight and topology of a node
+// The ring stores a copy of the node; later changes to n have no effect.
+func (r *RendezvousRing) UpdateNode(n *Node) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if n == nil {
+		return errors.New("cannot update nil node")
+	}
+
+	existing, exists := r.nodes[n.ID]
+	if !exists {
+		return ErrNodeNotFound
+	}
+
+	updated := *n
+	r.nodes[n.ID] = &rendezvousNode{node: &updated, seed: existing.seed}
+	return nil
+}
+
+// rendezvousScore returns the weighted score of a node for a key hash
+// It uses the logarithmic method: -weight / ln(u), where u is the key/node hash
+// mapped uniformly onto (0, 1). A node's chance of winning is proportional to its weight.
+func rendezvousScore(keyHash uint64, n *rendezvousNode) float64 {
+	mixed := fmix64(keyHash ^ n.seed)
+	u := (float64(mixed>>11) + 0.5) / (1 << 53)
+	return -float64(n.node.EffectiveWeight()) / math.Log(u)
+}
@@ -0,0 +1,59 @@
+package hashring
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestRendezvousOwnershipIsMeasured(t *testing.T) {
+	ring := NewRendezvousRing(WithHasher(XXHash64{}))
+	for i := 1; i <= 5; i++ {
+		if err := ring.AddNode(&Node{ID: fmt.Sprintf("node-%d", i), Weight: i, Status: NodeStatusActive}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ring.AddNode(&Node{ID: "node-down", Weight: 1, Status: NodeStatusInactive}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Count the owners of real keys to compare against the report
+	const keys = 200000
+	counts := make(map[string]int)
+	for i := 0; i < keys; i++ {
+		node, err := ring.GetNode(fmt.Sprintf("key-%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[node.ID]++
+	}
+
+	report := ring.Ownership()
+	if len(report.Nodes) != 5 {
+		t.Fatalf("expected 5 available nodes, got %d", len(report.Nodes))
+	}
+
+	total := 0.0
+	exact := 0
+	for _, node := range report.Nodes {
+		total += node.Share
+		if node.Share == node.Expected {
+			exact++
+		}
+
+		observed := float64(counts[node.NodeID]) / keys
+		if math.Abs(node.Share-observed) > 0.01 {
+			t.Errorf("%s: reported share %.4f, observed %.4f", node.NodeID, node.Share, observed)
+		}
+		if math.Abs(node.Share-node.Expected) > 0.01 {
+			t.Errorf("%s: share %.4f too far from expected %.4f", node.NodeID, node.Share, node.Expected)
+		}
+	}
+
+	if math.Abs(total-1) > 1e-9 {
+		t.Errorf("shares sum to %f", total)
+	}
+	if exact == len(report.Nodes) {
+		t.Error("report echoes the configured weights instead of measuring")
+	}
+}
@@ -0,0 +1,283 @@
+package hashring
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// stateMagic prefixes the binary encoding of a ring state
+var stateMagic = []byte("PRNG")
+
+// stateVersion is the version of the binary encoding
+const stateVersion = 1
+
+// State is the complete, serializable state of a HashRing
+// Restoring a State on another process yields a ring that routes every key
+// to the same node.
+type State struct {
+	// Epoch is the version of the ring; every change increments it
+	Epoch uint64 `json:"epoch"`
+
+	// ReplicaCount is the number of virtual nodes per unit of node weight
+	ReplicaCount int `json:"replica_count"`
+
+	// Hasher is the name of the hash function (see HasherByName)
+	Hasher string `json:"hasher"`
+
+	// Nodes are the nodes in the ring, sorted by ID
+	Nodes []Node `json:"nodes"`
+}
+
+// State returns the current state of the ring
+// It fails with ErrUnknownHasher if the ring uses an unnamed custom hasher.
+func (h *HashRing) State() (*State, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	snapshot := h.snapshot.Load()
+
+	name, err := hasherName(snapshot.config.hasher)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]Node, 0, len(snapshot.nodes))
+	for _, node := range snapshot.nodes {
+		nodes = append(nodes, *node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	return &State{
+		Epoch:        snapshot.epoch,
+		ReplicaCount: h.replicaCount,
+		Hasher:       name,
+		Nodes:        nodes,
+	}, nil
+}
+
+// Restore replaces the ring's nodes, replica count and hasher with the given state
+// The bounded-load setting of the ring is kept. States older than the ring's
+// current epoch are rejected with ErrStaleEpoch; restoring the current epoch
+// again is allowed.
+func (h *HashRing) Restore(state *State) error {
+	if state == nil {
+		return fmt.Errorf("%w: nil state", ErrInvalidState)
+	}
+
+	if state.ReplicaCount <= 0 {
+		return fmt.Errorf("%w: replica count must be greater than 0", ErrInvalidState)
+	}
+
+	hasher, err := HasherByName(state.Hasher)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	current := h.snapshot.Load()
+	if state.Epoch < current.epoch {
+		return ErrStaleEpoch
+	}
+
+	seen := make(map[string]bool, len(state.Nodes))
+	for _, node := range state.Nodes {
+		if node.ID == "" {
+			return fmt.Errorf("%w: node ID cannot be empty", ErrInvalidState)
+		}
+		if seen[node.ID] {
+			return fmt.Errorf("%w: duplicate node %s", ErrInvalidState, node.ID)
+		}
+		seen[node.ID] = true
+	}
+
+	cfg := *h.config
+	cfg.hasher = hasher
+	h.config = &cfg
+	h.replicaCount = state.ReplicaCount
+
+	next := &Snapshot{
+		nodes:  make(map[string]*Node, len(state.Nodes)),
+		hashes: make([]uint64, 0),
+		owners: make([]string, 0),
+		config: &cfg,
+		epoch:  state.Epoch,
+	}
+
+	for i := range state.Nodes {
+		node := state.Nodes[i]
+		next.nodes[node.ID] = &node
+		next.addVirtualNodes(h.virtualNodeHashes(node.ID, 0, h.virtualNodeCount(node.EffectiveWeight())), node.ID)
+	}
+
+	h.snapshot.Store(next)
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (h *HashRing) MarshalJSON() ([]byte, error) {
+	state, err := h.State()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(state)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+// The ring must have been created with NewHashRing.
+func (h *HashRing) UnmarshalJSON(data []byte) error {
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	return h.Restore(&state)
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface
+func (h *HashRing) MarshalBinary() ([]byte, error) {
+	state, err := h.State()
+	if err != nil {
+		return nil, err
+	}
+	return state.MarshalBinary()
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface
+// The ring must have been created with NewHashRing.
+func (h *HashRing) UnmarshalBinary(data []byte) error {
+	var state State
+	if err := state.UnmarshalBinary(data); err != nil {
+		return err
+	}
+	return h.Restore(&state)
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface
+// The format is a magic prefix and version byte followed by varint-encoded
+// integers and length-prefixed strings.
+func (s *State) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, 64+len(s.Nodes)*48)
+	buf = append(buf, stateMagic...)
+	buf = append(buf, stateVersion)
+	buf = binary.AppendUvarint(buf, s.Epoch)
+	buf = binary.AppendUvarint(buf, uint64(s.ReplicaCount))
+	buf = appendString(buf, s.Hasher)
+	buf = binary.AppendUvarint(buf, uint64(len(s.Nodes)))
+
+	for _, node := range s.Nodes {
+		buf = appendString(buf, node.ID)
+		buf = appendString(buf, node.Address)
+		buf = appendString(buf, string(node.Status))
+		buf = binary.AppendVarint(buf, int64(node.Weight))
+		buf = binary.AppendVarint(buf, node.LastHeartbeat)
+		buf = appendString(buf, node.Topology.Region)
+		buf = appendString(buf, node.Topology.Zone)
+		buf = appendString(buf, node.Topology.Rack)
+	}
+
+	return buf, nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface
+func (s *State) UnmarshalBinary(data []byte) error {
+	if len(data) < len(stateMagic)+1 || string(data[:len(stateMagic)]) != string(stateMagic) {
+		return fmt.Errorf("%w: bad magic", ErrInvalidState)
+	}
+
+	if version := data[len(stateMagic)]; version != stateVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidState, version)
+	}
+
+	r := &stateReader{data: data[len(stateMagic)+1:]}
+
+	s.Epoch = r.uvarint()
+	s.ReplicaCount = int(r.uvarint())
+	s.Hasher = r.string()
+
+	count := r.uvarint()
+	if r.err == nil && count > uint64(len(r.data)) {
+		// Every node takes at least one byte; reject absurd counts before allocating
+		return fmt.Errorf("%w: node count %d exceeds input", ErrInvalidState, count)
+	}
+
+	s.Nodes = make([]Node, 0, count)
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		node := Node{
+			ID:      r.string(),
+			Address: r.string(),
+			Status:  NodeStatus(r.string()),
+		}
+		node.Weight = int(r.varint())
+		node.LastHeartbeat = r.varint()
+		node.Topology.Region = r.string()
+		node.Topology.Zone = r.string()
+		node.Topology.Rack = r.string()
+		s.Nodes = append(s.Nodes, node)
+	}
+
+	if r.err != nil {
+		return r.err
+	}
+
+	if len(r.data) != 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrInvalidState, len(r.data))
+	}
+
+	return nil
+}
+
+// appendString appends a length-prefixed string
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// stateReader decodes the binary state encoding, remembering the first error
+type stateReader struct {
+	data []byte
+	err  error
+}
+
+func (r *stateReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	value, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = fmt.Errorf("%w: truncated integer", ErrInvalidState)
+		return 0
+	}
+	r.data = r.data[n:]
+	return value
+}
+
+func (r *stateReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	value, n := binary.Varint(r.data)
+	if n <= 0 {
+		r.err = fmt.Errorf("%w: truncated integer", ErrInvalidState)
+		return 0
+	}
+	r.data = r.data[n:]
+	return value
+}
+
+func (r *stateReader) string() string {
+	length := r.uvarint()
+	if r.err != nil {
+		return ""
+	}
+	if length > uint64(len(r.data)) || length > math.MaxInt32 {
+		r.err = fmt.Errorf("%w: truncated string", ErrInvalidState)
+		return ""
+	}
+	value := string(r.data[:length])
+	r.data = r.data[length:]
+	return value
+}
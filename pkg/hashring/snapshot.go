@@ -0,0 +1,240 @@
+package hashring
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Snapshot is an immutable view of a HashRing at a point in time
+// It is safe for concurrent use without locking. The nodes it returns are
+// copies, so modifying them does not affect the snapshot.
+type Snapshot struct {
+	nodes  map[string]*Node // Map of node ID to node
+	hashes []uint64         // Sorted list of virtual node hashes
+	owners []string         // owners[i] is the ID of the node owning hashes[i]
+	config *config          // Ring settings such as the hash function
+	epoch  uint64           // Incremented by every change to the ring
+}
+
+// Epoch returns the version of the ring this snapshot was taken from
+// Every change to a ring publishes a snapshot with a higher epoch.
+func (s *Snapshot) Epoch() uint64 {
+	return s.epoch
+}
+
+// GetNode returns the node responsible for the given key
+func (s *Snapshot) GetNode(key string) (*Node, error) {
+	node, err := s.getNode(key)
+	if err != nil {
+		return nil, err
+	}
+	return node.clone(), nil
+}
+
+// getNode returns the node responsible for the given key, shared with the snapshot
+func (s *Snapshot) getNode(key string) (*Node, error) {
+	if len(s.nodes) == 0 {
+		return nil, ErrNoNodes
+	}
+
+	// Find the first virtual node with hash >= key hash
+	idx := s.search(s.config.hashKey(key))
+
+	// Get the node ID from the virtual node
+	nodeID := s.owners[idx]
+	node, exists := s.nodes[nodeID]
+
+	if !exists {
+		// This should never happen if internal state is consistent
+		return nil, fmt.Errorf("internal error: virtual node points to non-existent node %s", nodeID)
+	}
+
+	// If the node is not active, find the next active node
+	if !node.IsAvailable() {
+		return s.getNextAvailableNode(idx)
+	}
+
+	return node, nil
+}
+
+// GetNodesForKeys returns the node responsible for each of the given keys
+func (s *Snapshot) GetNodesForKeys(keys []string) (map[string]*Node, error) {
+	result := make(map[string]*Node, len(keys))
+	for _, key := range keys {
+		node, err := s.getNode(key)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = node.clone()
+	}
+	return result, nil
+}
+
+// GetNodesN walks the ring clockwise from the key and returns up to n distinct available nodes
+// When nodes carry topology, replicas are spread across zones and racks.
+func (s *Snapshot) GetNodesN(key string, n int) ([]*Node, error) {
+	if len(s.nodes) == 0 {
+		return nil, ErrNoNodes
+	}
+
+	if n <= 0 {
+		return []*Node{}, nil
+	}
+
+	idx := s.search(s.config.hashKey(key))
+
+	limit := candidateLimit(s.nodes, n)
+	result := make([]*Node, 0, limit)
+	seen := make(map[string]bool, len(s.nodes))
+	for i := 0; i < len(s.hashes) && len(result) < limit && len(seen) < len(s.nodes); i++ {
+		nodeID := s.owners[(idx+i)%len(s.hashes)]
+		if seen[nodeID] {
+			continue
+		}
+		seen[nodeID] = true
+
+		if node := s.nodes[nodeID]; node.IsAvailable() {
+			result = append(result, node)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, ErrNoActiveNodes
+	}
+
+	return cloneNodes(selectReplicas(result, n)), nil
+}
+
+// GetNodes returns copies of all nodes in the snapshot
+func (s *Snapshot) GetNodes() []*Node {
+	nodes := make([]*Node, 0, len(s.nodes))
+	for _, node := range s.nodes {
+		copied := *node
+		nodes = append(nodes, &copied)
+	}
+	return nodes
+}
+
+// GetNodeCount returns the number of nodes in the snapshot
+func (s *Snapshot) GetNodeCount() int {
+	return len(s.nodes)
+}
+
+// search returns the index of the first virtual node with hash >= the given hash,
+// wrapping around to the first virtual node at the end of the ring
+func (s *Snapshot) search(hash uint64) int {
+	idx := sort.Search(len(s.hashes), func(i int) bool {
+		return s.hashes[i] >= hash
+	})
+
+	if idx >= len(s.hashes) {
+		idx = 0
+	}
+
+	return idx
+}
+
+// getNextAvailableNode finds the next available node starting from the given index
+func (s *Snapshot) getNextAvailableNode(startIdx int) (*Node, error) {
+	for i := 0; i < len(s.hashes); i++ {
+		node, exists := s.nodes[s.owners[(startIdx+i)%len(s.hashes)]]
+		if exists && node.IsAvailable() {
+			return node, nil
+		}
+	}
+
+	return nil, ErrNoActiveNodes
+}
+
+// withNode returns a copy of the snapshot with the given node added or replaced
+// The virtual nodes are shared with the receiver.
+func (s *Snapshot) withNode(node *Node) *Snapshot {
+	nodes := make(map[string]*Node, len(s.nodes)+1)
+	for id, existing := range s.nodes {
+		nodes[id] = existing
+	}
+	nodes[node.ID] = node
+
+	return &Snapshot{
+		nodes:  nodes,
+		hashes: s.hashes,
+		owners: s.owners,
+		config: s.config,
+		epoch:  s.epoch + 1,
+	}
+}
+
+// withoutNode returns a copy of the snapshot without the given node
+// The virtual nodes are shared with the receiver.
+func (s *Snapshot) withoutNode(nodeID string) *Snapshot {
+	nodes := make(map[string]*Node, len(s.nodes))
+	for id, existing := range s.nodes {
+		if id != nodeID {
+			nodes[id] = existing
+		}
+	}
+
+	return &Snapshot{
+		nodes:  nodes,
+		hashes: s.hashes,
+		owners: s.owners,
+		config: s.config,
+		epoch:  s.epoch + 1,
+	}
+}
+
+// addVirtualNodes places virtual nodes owned by nodeID on an unpublished snapshot
+// Fresh slices are allocated, so snapshots sharing the old ones are unaffected.
+func (s *Snapshot) addVirtualNodes(hashes []uint64, nodeID string) {
+	added := make([]uint64, len(hashes))
+	copy(added, hashes)
+	sort.Slice(added, func(i, j int) bool { return added[i] < added[j] })
+
+	merged := make([]uint64, 0, len(s.hashes)+len(added))
+	owners := make([]string, 0, len(s.hashes)+len(added))
+
+	i, j := 0, 0
+	for i < len(s.hashes) || j < len(added) {
+		switch {
+		case j >= len(added) || (i < len(s.hashes) && s.hashes[i] < added[j]):
+			merged = append(merged, s.hashes[i])
+			owners = append(owners, s.owners[i])
+			i++
+		case i < len(s.hashes) && s.hashes[i] == added[j]:
+			// Extremely unlikely with a 64-bit ring; keep the first owner
+			j++
+		case len(merged) > 0 && merged[len(merged)-1] == added[j]:
+			// Duplicate within the added hashes
+			j++
+		default:
+			merged = append(merged, added[j])
+			owners = append(owners, nodeID)
+			j++
+		}
+	}
+
+	s.hashes = merged
+	s.owners = owners
+}
+
+// removeVirtualNodes takes virtual nodes owned by nodeID off an unpublished snapshot
+// Fresh slices are allocated, so snapshots sharing the old ones are unaffected.
+func (s *Snapshot) removeVirtualNodes(hashes []uint64, nodeID string) {
+	removed := make(map[uint64]bool, len(hashes))
+	for _, hash := range hashes {
+		removed[hash] = true
+	}
+
+	kept := make([]uint64, 0, len(s.hashes))
+	owners := make([]string, 0, len(s.hashes))
+	for i, hash := range s.hashes {
+		if s.owners[i] == nodeID && removed[hash] {
+			continue
+		}
+		kept = append(kept, hash)
+		owners = append(owners, s.owners[i])
+	}
+
+	s.hashes = kept
+	s.owners = owners
+}
@@ -0,0 +1,110 @@
+package hashring
+
+// Topology describes the failure domains a node runs in
+// Replica selection uses it to spread the owners of a key across zones and racks.
+type Topology struct {
+	// Region is the geographic region, e.g. "eu-west-1"
+	Region string `json:"region,omitempty"`
+
+	// Zone is the availability zone within the region, e.g. "eu-west-1a"
+	Zone string `json:"zone,omitempty"`
+
+	// Rack is the rack or host group within the zone
+	Rack string `json:"rack,omitempty"`
+}
+
+// IsZero reports whether no topology information is set
+func (t Topology) IsZero() bool {
+	return t.Region == "" && t.Zone == "" && t.Rack == ""
+}
+
+// zoneKey identifies the zone failure domain of a topology
+func (t Topology) zoneKey() string {
+	return t.Region + "/" + t.Zone
+}
+
+// rackKey identifies the rack failure domain of a topology
+func (t Topology) rackKey() string {
+	return t.Region + "/" + t.Zone + "/" + t.Rack
+}
+
+// anyTopology reports whether at least one of the nodes carries topology information
+func anyTopology(nodes []*Node) bool {
+	for _, node := range nodes {
+		if !node.Topology.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// candidateLimit returns how many preference-ordered candidates a ring must collect
+// to pick n replicas: n without topology, every node with it
+// The first replica never depends on topology.
+func candidateLimit(nodes map[string]*Node, n int) int {
+	if n <= 1 {
+		return n
+	}
+
+	for _, node := range nodes {
+		if !node.Topology.IsZero() {
+			return len(nodes)
+		}
+	}
+	return n
+}
+
+// selectReplicas picks up to n nodes from a preference-ordered candidate list,
+// spreading them across failure domains
+// Nodes in zones not yet used are taken first, then nodes on racks not yet used,
+// and only then the remaining nodes in preference order. The first candidate is
+// always selected first, so the primary owner does not depend on topology.
+func selectReplicas(candidates []*Node, n int) []*Node {
+	if n >= len(candidates) && !anyTopology(candidates) {
+		return candidates
+	}
+
+	selected := make([]*Node, 0, n)
+	taken := make([]bool, len(candidates))
+	zones := make(map[string]bool)
+	racks := make(map[string]bool)
+
+	take := func(i int) {
+		taken[i] = true
+		selected = append(selected, candidates[i])
+		zones[candidates[i].Topology.zoneKey()] = true
+		racks[candidates[i].Topology.rackKey()] = true
+	}
+
+	// First pass: one node per zone
+	for i, node := range candidates {
+		if len(selected) == n {
+			return selected
+		}
+		if !zones[node.Topology.zoneKey()] {
+			take(i)
+		}
+	}
+
+	// Second pass: too few zones, spread across racks
+	for i, node := range candidates {
+		if len(selected) == n {
+			return selected
+		}
+		if !taken[i] && !racks[node.Topology.rackKey()] {
+			take(i)
+		}
+	}
+
+	// Final pass: fill up in preference order
+	for i := range candidates {
+		if len(selected) == n {
+			return selected
+		}
+		if !taken[i] {
+			take(i)
+		}
+	}
+
+	return selected
+}
@@ -1,6 +1,8 @@
 package hashring
 
 // Ring defines the interface for a consistent hash ring
+// Rings are safe for concurrent use. Every node a ring returns is a copy, so
+// callers may modify it without affecting the ring.
 type Ring interface {
 	// AddNode adds a new node to the hash ring
 	AddNode(node *Node) error
@@ -11,6 +13,14 @@ type Ring interface {
 	// GetNode returns the node responsible for the given key
 	GetNode(key string) (*Node, error)
 
+	// GetNodesForKeys resolves a batch of keys against a single consistent view of the ring
+	GetNodesForKeys(keys []string) (map[string]*Node, error)
+
+	// GetNodesN returns up to n distinct available nodes for the given key in preference order
+	// The first node is the one GetNode returns; fewer than n nodes are returned
+	// if fewer are available.
+	GetNodesN(key string, n int) ([]*Node, error)
+
 	// GetNodes returns all nodes in the hash ring
 	GetNodes() []*Node
 
@@ -19,4 +29,28 @@ type Ring interface {
 
 	// UpdateNodeStatus updates a node's status
 	UpdateNodeStatus(nodeID string, status NodeStatus) error
+
+	// UpdateNodeWeight changes a node's weight, moving only the keys affected by the change
+	UpdateNodeWeight(nodeID string, weight int) error
+
+	// UpdateNode replaces the address, status, weight and topology of a node already in the ring
+	// Only the keys affected by a weight or status change move.
+	UpdateNode(node *Node) error
+
+	// Ownership reports the share of the hash space and the token ranges each available node owns
+	Ownership() *OwnershipReport
+}
+
+// LoadAware is implemented by rings that support consistent hashing with bounded loads
+// The ring itself does not know how many keys each node holds, so the caller
+// reports loads and keeps them up to date as keys are assigned.
+type LoadAware interface {
+	// BoundedLoad reports whether bounded-load mode is enabled
+	BoundedLoad() bool
+
+	// SetLoads replaces the current per-node key counts
+	SetLoads(loads map[string]int)
+
+	// AddLoad adjusts the key count of a node by delta
+	AddLoad(nodeID string, delta int)
 }
@@ -0,0 +1,12 @@
+package swim
+
+import "errors"
+
+// ErrJoinFailed is returned when none of the seeds answered a join request
+var ErrJoinFailed = errors.New("no seed answered the join request")
+
+// ErrShutdown is returned when operating on a memberlist that has been shut down
+var ErrShutdown = errors.New("memberlist has been shut down")
+
+// ErrInvalidID is returned when creating a memberlist without a member ID
+var ErrInvalidID = errors.New("member ID cannot be empty")
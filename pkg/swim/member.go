@@ -0,0 +1,89 @@
+package swim
+
+// State is the state of a member as seen by the cluster
+type State string
+
+const (
+	// StateAlive; the member answers probes
+	StateAlive State = "alive"
+	// StateSuspect; the member missed a probe and can still refute the suspicion
+	StateSuspect State = "suspect"
+	// StateDead; the member stayed suspect for the suspicion timeout
+	StateDead State = "dead"
+	// StateLeft; the member left the cluster gracefully
+	StateLeft State = "left"
+)
+
+// Member is a node of the gossip cluster
+type Member struct {
+	// ID is the unique identifier of the member
+	ID string `json:"id"`
+
+	// Addr is the UDP address of the member
+	Addr string `json:"addr"`
+
+	// State is the state of the member
+	State State `json:"state"`
+
+	// Incarnation orders the updates about a member; only the member itself
+	// increments it, to refute suspicion
+	Incarnation uint64 `json:"incarnation"`
+
+	// Meta is the metadata the member gossips about itself
+	Meta map[string]string `json:"meta,omitempty"`
+}
+
+// IsActive reports whether the member is alive or suspect
+func (m *Member) IsActive() bool {
+	return m.State == StateAlive || m.State == StateSuspect
+}
+
+// supersedes reports whether update u should replace the known state m
+// Higher incarnations always win. At the same incarnation suspect overrides
+// alive, and dead or left override both.
+func (u *Member) supersedes(m *Member) bool {
+	if u.Incarnation != m.Incarnation {
+		return u.Incarnation > m.Incarnation
+	}
+	return stateRank(u.State) > stateRank(m.State)
+}
+
+// stateRank orders the states that can be reached at one incarnation
+func stateRank(state State) int {
+	switch state {
+	case StateAlive:
+		return 0
+	case StateSuspect:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// EventType is the kind of change an Event reports
+type EventType string
+
+const (
+	// EventJoin; a member joined, or rejoined after leaving
+	EventJoin EventType = "join"
+	// EventSuspect; a member is suspected to have failed
+	EventSuspect EventType = "suspect"
+	// EventAlive; a suspect or dead member is alive again
+	EventAlive EventType = "alive"
+	// EventDead; a member is considered dead
+	EventDead EventType = "dead"
+	// EventLeave; a member left the cluster
+	EventLeave EventType = "leave"
+)
+
+// Event reports a change in the state of a member
+type Event struct {
+	// Type is the kind of change
+	Type EventType
+
+	// Member is the member after the change
+	Member Member
+
+	// Previous is the state of the member before the change, empty for new members
+	Previous State
+}
@@ -0,0 +1,93 @@
+package swim
+
+import "encoding/json"
+
+// maxPacketSize is the largest datagram read or written
+const maxPacketSize = 65507
+
+// syncOverhead is room left in a sync message for everything but its updates
+const syncOverhead = 512
+
+// maxPiggyback is the largest number of updates piggybacked on one message
+const maxPiggyback = 16
+
+// messageType is the kind of a protocol message
+type messageType string
+
+const (
+	// msgPing asks the receiver for an ack
+	msgPing messageType = "ping"
+	// msgAck answers a ping or a ping-req
+	msgAck messageType = "ack"
+	// msgPingReq asks the receiver to ping the target and relay the ack
+	msgPingReq messageType = "ping-req"
+	// msgJoin asks the receiver for the full member list
+	msgJoin messageType = "join"
+	// msgSync carries the full member list in answer to a join, split over
+	// several messages when it does not fit in one datagram
+	msgSync messageType = "sync"
+)
+
+// message is a protocol message; every message piggybacks membership updates
+type message struct {
+	Type messageType `json:"type"`
+
+	// Seq matches acks and syncs to the ping or join they answer
+	Seq uint64 `json:"seq,omitempty"`
+
+	// From is the ID of the sender
+	From string `json:"from"`
+
+	// Target is the ID of the member a ping is meant for, or the member a
+	// ping-req asks to probe
+	Target string `json:"target,omitempty"`
+
+	// TargetAddr is the address of the member a ping-req asks to probe
+	TargetAddr string `json:"target_addr,omitempty"`
+
+	// Updates are the piggybacked membership updates
+	Updates []Member `json:"updates,omitempty"`
+}
+
+// encode serializes a message
+func (m *message) encode() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// decodeMessage parses a message
+func decodeMessage(data []byte) (*message, error) {
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// broadcast is a membership update waiting to be piggybacked
+type broadcast struct {
+	update    Member
+	transmits int
+}
+
+// pendingAck is a probe or join waiting for its answer
+type pendingAck struct {
+	// ch receives the first accepted answer
+	ch chan *message
+	// kind is the type of message that answers it
+	kind messageType
+	// senders are the IDs of the members allowed to answer, nil accepts any
+	senders map[string]struct{}
+}
+
+// accepts reports whether msg answers the pending probe or join
+// The caller must hold the memberlist lock.
+func (p *pendingAck) accepts(msg *message) bool {
+	if msg.Type != p.kind {
+		return false
+	}
+	if p.senders == nil {
+		return true
+	}
+	_, ok := p.senders[msg.From]
+	return ok
+}
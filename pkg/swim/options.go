@@ -0,0 +1,133 @@
+package swim
+
+import "time"
+
+// Option configures a memberlist at construction time
+type Option func(*config)
+
+// config holds the protocol settings of a memberlist
+type config struct {
+	// advertiseAddr; the address other members reach this member at, empty uses the bound address
+	advertiseAddr string
+	// meta; metadata gossiped with this member, e.g. its weight and topology
+	meta map[string]string
+	// probeInterval; the time between two probes of a random member
+	probeInterval time.Duration
+	// probeTimeout; how long to wait for a direct ack before probing indirectly
+	probeTimeout time.Duration
+	// indirectChecks; the number of members asked to probe a target indirectly
+	indirectChecks int
+	// suspicionTimeout; how long a member stays suspect before it is declared dead
+	suspicionTimeout time.Duration
+	// retransmitMult; each update is piggybacked retransmitMult * log10(n+1) times
+	retransmitMult int
+	// eventBuffer; the capacity of the events channel
+	eventBuffer int
+	// reapTimeout; how long dead and left members are remembered before they are forgotten
+	reapTimeout time.Duration
+}
+
+// newConfig builds a config from the given options, applying defaults
+func newConfig(opts ...Option) *config {
+	cfg := &config{
+		probeInterval:    time.Second,
+		probeTimeout:     500 * time.Millisecond,
+		indirectChecks:   3,
+		suspicionTimeout: 5 * time.Second,
+		retransmitMult:   4,
+		eventBuffer:      64,
+		reapTimeout:      time.Minute,
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(cfg)
+		}
+	}
+
+	// The indirect probes need part of the probe interval
+	if cfg.probeTimeout >= cfg.probeInterval {
+		cfg.probeTimeout = cfg.probeInterval / 2
+	}
+
+	return cfg
+}
+
+// WithAdvertiseAddr sets the address other members reach this member at
+// Use it when the bound address is not reachable, e.g. when binding 0.0.0.0.
+func WithAdvertiseAddr(addr string) Option {
+	return func(c *config) {
+		c.advertiseAddr = addr
+	}
+}
+
+// WithMeta sets metadata gossiped with this member
+func WithMeta(meta map[string]string) Option {
+	return func(c *config) {
+		c.meta = make(map[string]string, len(meta))
+		for key, value := range meta {
+			c.meta[key] = value
+		}
+	}
+}
+
+// WithProbeInterval sets the time between two probes and the probe timeout
+// A timeout that does not leave room for indirect probes is halved to fit.
+func WithProbeInterval(interval, timeout time.Duration) Option {
+	return func(c *config) {
+		if interval > 0 {
+			c.probeInterval = interval
+		}
+		if timeout > 0 {
+			c.probeTimeout = timeout
+		}
+	}
+}
+
+// WithIndirectChecks sets the number of members asked to probe a target that missed a direct probe
+func WithIndirectChecks(count int) Option {
+	return func(c *config) {
+		if count >= 0 {
+			c.indirectChecks = count
+		}
+	}
+}
+
+// WithSuspicionTimeout sets how long a member stays suspect before it is declared dead
+func WithSuspicionTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		if timeout > 0 {
+			c.suspicionTimeout = timeout
+		}
+	}
+}
+
+// WithRetransmitMult sets how many times updates are piggybacked, scaled by log10 of the cluster size
+func WithRetransmitMult(mult int) Option {
+	return func(c *config) {
+		if mult > 0 {
+			c.retransmitMult = mult
+		}
+	}
+}
+
+// WithEventBuffer sets the capacity of the events channel
+func WithEventBuffer(size int) Option {
+	return func(c *config) {
+		if size >= 0 {
+			c.eventBuffer = size
+		}
+	}
+}
+
+// WithReapTimeout sets how long dead and left members are remembered
+// Until then they are listed by Members and their state is sent to joining
+// members; afterwards they are forgotten, so departed members do not
+// accumulate.
+func WithReapTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		if timeout > 0 {
+			c.reapTimeout = timeout
+		}
+	}
+}
@@ -0,0 +1,788 @@
+package swim
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+)
+
+// Memberlist runs the SWIM membership protocol for one member
+// Every probe interval it pings a member in round-robin order. If no ack
+// arrives in time, it asks a few other members to ping the target as well,
+// and suspects the target if none of them gets an ack either. Suspects are
+// declared dead after the suspicion timeout unless they refute the suspicion
+// with a higher incarnation. Membership updates are piggybacked on the probe
+// messages, so no extra traffic is needed to disseminate them.
+type Memberlist struct {
+	config *config
+	conn   *net.UDPConn
+	selfID string
+
+	mu         sync.Mutex             // Protects the fields below
+	members    map[string]*Member     // All known members, including this one
+	gone       map[string]time.Time   // When dead and left members were last updated, for reaping
+	suspects   map[string]*time.Timer // Suspicion timers by member ID
+	broadcasts []*broadcast           // Updates waiting to be piggybacked
+	probeOrder []string               // Member IDs in probing order
+	probeIndex int                    // Position of the next member to probe
+	acks       map[uint64]*pendingAck // Pending acks and syncs by sequence number
+	seq        uint64                 // Last sequence number used
+	shutdown   bool                   // Set once Shutdown has been called
+
+	events  chan Event
+	eventMu sync.RWMutex // Held for writing while the events channel is closed
+	closed  bool         // Set once the events channel is closed
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// New creates a memberlist for the member with the given ID listening on the given UDP address
+// Binding "127.0.0.1:0" picks a free loopback port, which is convenient for
+// running several members in one process. Call Join to contact an existing
+// cluster; a memberlist that joins no one starts a new cluster.
+func New(id, bindAddr string, opts ...Option) (*Memberlist, error) {
+	if id == "" {
+		return nil, ErrInvalidID
+	}
+
+	cfg := newConfig(opts...)
+
+	addr, err := net.ResolveUDPAddr("udp", bindAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	advertise := cfg.advertiseAddr
+	if advertise == "" {
+		advertise = conn.LocalAddr().String()
+	}
+
+	// Incarnations start at the current time, so a restarted member outranks
+	// whatever the cluster remembers about its previous run
+	self := &Member{
+		ID:          id,
+		Addr:        advertise,
+		State:       StateAlive,
+		Incarnation: uint64(time.Now().UnixMilli()),
+		Meta:        cfg.meta,
+	}
+
+	m := &Memberlist{
+		config:   cfg,
+		conn:     conn,
+		selfID:   id,
+		members:  map[string]*Member{id: self},
+		gone:     make(map[string]time.Time),
+		suspects: make(map[string]*time.Timer),
+		acks:     make(map[uint64]*pendingAck),
+		// Random so a restarted member does not reuse the sequence numbers of
+		// its previous run, whose late acks may still be in flight
+		seq:    rand.Uint64() >> 1,
+		events: make(chan Event, cfg.eventBuffer),
+		done:   make(chan struct{}),
+	}
+
+	m.wg.Add(2)
+	go m.receiveLoop()
+	go m.probeLoop()
+
+	return m, nil
+}
+
+// Events returns the channel membership changes are sent on
+// The channel must be drained; the protocol blocks while it is full. It is
+// closed by Shutdown.
+func (m *Memberlist) Events() <-chan Event {
+	return m.events
+}
+
+// LocalMember returns this member
+func (m *Memberlist) LocalMember() Member {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return copyMember(m.members[m.selfID])
+}
+
+// Members returns every known member, including this member and dead or left
+// members that have not been reaped yet
+func (m *Memberlist) Members() []Member {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	members := make([]Member, 0, len(m.members))
+	for _, member := range m.members {
+		members = append(members, copyMember(member))
+	}
+	return members
+}
+
+// Join contacts the given seed addresses and merges their member lists
+// It returns the number of seeds that answered, and ErrJoinFailed if there
+// were seeds and none of them answered within the probe interval.
+func (m *Memberlist) Join(seeds []string) (int, error) {
+	type pending struct {
+		seq uint64
+		ch  chan *message
+	}
+
+	waiting := make([]pending, 0, len(seeds))
+	for _, seed := range seeds {
+		addr, err := net.ResolveUDPAddr("udp", seed)
+		if err != nil {
+			fmt.Printf("error resolving seed %s: %s\n", seed, err)
+			continue
+		}
+
+		// The seed's ID is not known yet, so any member may answer
+		seq, ch, err := m.expectAck(msgSync)
+		if err != nil {
+			return 0, err
+		}
+
+		m.mu.Lock()
+		self := copyMember(m.members[m.selfID])
+		m.mu.Unlock()
+
+		m.send(addr, &message{Type: msgJoin, Seq: seq, From: m.selfID, Updates: []Member{self}})
+		waiting = append(waiting, pending{seq: seq, ch: ch})
+	}
+
+	deadline := time.NewTimer(m.config.probeInterval)
+	defer deadline.Stop()
+
+	joined := 0
+	for _, p := range waiting {
+		select {
+		case <-p.ch:
+			joined++
+		case <-deadline.C:
+			// The deadline is shared; later seeds get no extra time
+			deadline.Reset(0)
+		case <-m.done:
+			return joined, ErrShutdown
+		}
+		m.forgetAck(p.seq)
+	}
+
+	if len(seeds) > 0 && joined == 0 {
+		return 0, ErrJoinFailed
+	}
+
+	return joined, nil
+}
+
+// Leave announces that this member is leaving and shuts the memberlist down
+// The announcement is sent to every active member directly, then the
+// memberlist waits for the given timeout so it can still answer probes.
+func (m *Memberlist) Leave(timeout time.Duration) error {
+	m.mu.Lock()
+	if m.shutdown {
+		m.mu.Unlock()
+		return ErrShutdown
+	}
+
+	self := m.members[m.selfID]
+	self.Incarnation++
+	self.State = StateLeft
+	update := copyMember(self)
+	m.enqueueBroadcast(update)
+
+	targets := make([]string, 0, len(m.members))
+	for id, member := range m.members {
+		if id != m.selfID && member.IsActive() {
+			targets = append(targets, member.Addr)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, target := range targets {
+		if addr, err := net.ResolveUDPAddr("udp", target); err == nil {
+			m.send(addr, &message{Type: msgPing, From: m.selfID, Updates: []Member{update}})
+		}
+	}
+
+	select {
+	case <-time.After(timeout):
+	case <-m.done:
+	}
+
+	return m.Shutdown()
+}
+
+// Shutdown stops the protocol without announcing it; the other members will
+// eventually declare this member dead
+func (m *Memberlist) Shutdown() error {
+	m.mu.Lock()
+	if m.shutdown {
+		m.mu.Unlock()
+		return nil
+	}
+	m.shutdown = true
+	for _, timer := range m.suspects {
+		timer.Stop()
+	}
+	m.mu.Unlock()
+
+	close(m.done)
+	err := m.conn.Close()
+	m.wg.Wait()
+
+	// Suspicion timers may still be emitting events
+	m.eventMu.Lock()
+	m.closed = true
+	close(m.events)
+	m.eventMu.Unlock()
+
+	return err
+}
+
+// receiveLoop reads and handles messages until shutdown
+func (m *Memberlist) receiveLoop() {
+	defer m.wg.Done()
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, from, err := m.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-m.done:
+				return
+			default:
+				fmt.Printf("error reading gossip message: %s\n", err)
+				continue
+			}
+		}
+
+		msg, err := decodeMessage(buf[:n])
+		if err != nil {
+			fmt.Printf("error decoding gossip message from %s: %s\n", from, err)
+			continue
+		}
+
+		m.handle(msg, from)
+	}
+}
+
+// handle applies the updates of a message and answers it
+func (m *Memberlist) handle(msg *message, from *net.UDPAddr) {
+	m.applyUpdates(msg.Updates)
+
+	switch msg.Type {
+	case msgPing:
+		// Pings meant for a previous member at this address are not acked
+		if msg.Target != "" && msg.Target != m.selfID {
+			return
+		}
+		if msg.Seq != 0 {
+			m.send(from, m.newMessage(msgAck, msg.Seq))
+		}
+
+	case msgAck, msgSync:
+		// The senders of a pending ack change under the lock as relays are added
+		m.mu.Lock()
+		pending, ok := m.acks[msg.Seq]
+		accepted := ok && pending.accepts(msg)
+		m.mu.Unlock()
+		if accepted {
+			select {
+			case pending.ch <- msg:
+			default:
+			}
+		}
+
+	case msgPingReq:
+		// The receive loop is still running, so the wait group cannot be done yet
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			m.relayPing(msg, from)
+		}()
+
+	case msgJoin:
+		m.sendSync(from, msg.Seq)
+	}
+}
+
+// sendSync sends the full member list to a joining member
+// The list is split over as many sync messages as needed to keep each one
+// within maxPacketSize; every message carries the sequence number of the join.
+func (m *Memberlist) sendSync(to *net.UDPAddr, seq uint64) {
+	m.mu.Lock()
+	members := make([]Member, 0, len(m.members))
+	for _, member := range m.members {
+		members = append(members, copyMember(member))
+	}
+	m.mu.Unlock()
+
+	batch := make([]Member, 0, len(members))
+	size := 0
+	for _, member := range members {
+		encoded, err := json.Marshal(&member)
+		if err != nil {
+			fmt.Printf("error encoding member %s: %s\n", member.ID, err)
+			continue
+		}
+
+		// One byte for the separating comma
+		if len(batch) > 0 && size+len(encoded)+1 > maxPacketSize-syncOverhead {
+			m.send(to, &message{Type: msgSync, Seq: seq, From: m.selfID, Updates: batch})
+			batch = make([]Member, 0, len(members))
+			size = 0
+		}
+
+		batch = append(batch, member)
+		size += len(encoded) + 1
+	}
+
+	m.send(to, &message{Type: msgSync, Seq: seq, From: m.selfID, Updates: batch})
+}
+
+// relayPing pings the target of a ping-req and forwards its ack to the requester
+func (m *Memberlist) relayPing(req *message, requester *net.UDPAddr) {
+	target, err := net.ResolveUDPAddr("udp", req.TargetAddr)
+	if err != nil {
+		return
+	}
+
+	seq, ch, err := m.expectAck(msgAck, req.Target)
+	if err != nil {
+		return
+	}
+	defer m.forgetAck(seq)
+
+	ping := m.newMessage(msgPing, seq)
+	ping.Target = req.Target
+	m.send(target, ping)
+
+	select {
+	case <-ch:
+		m.send(requester, m.newMessage(msgAck, req.Seq))
+	case <-time.After(m.config.probeTimeout):
+	case <-m.done:
+	}
+}
+
+// probeLoop probes one member every probe interval until shutdown
+func (m *Memberlist) probeLoop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.config.probeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.reap()
+			m.probe()
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// probe pings the next member, falls back to indirect probes, and suspects
+// the member if no ack arrives before the end of the probe interval
+func (m *Memberlist) probe() {
+	target, ok := m.nextProbeTarget()
+	if !ok {
+		return
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", target.Addr)
+	if err != nil {
+		return
+	}
+
+	seq, ch, err := m.expectAck(msgAck, target.ID)
+	if err != nil {
+		return
+	}
+	defer m.forgetAck(seq)
+
+	ping := m.newMessage(msgPing, seq)
+	ping.Target = target.ID
+	m.send(addr, ping)
+
+	select {
+	case <-ch:
+		return
+	case <-time.After(m.config.probeTimeout):
+	case <-m.done:
+		return
+	}
+
+	// Ask other members to probe the target; their acks reuse our sequence number
+	for _, relay := range m.randomMembers(m.config.indirectChecks, target.ID) {
+		relayAddr, err := net.ResolveUDPAddr("udp", relay.Addr)
+		if err != nil {
+			continue
+		}
+		m.acceptAckFrom(seq, relay.ID)
+		req := m.newMessage(msgPingReq, seq)
+		req.Target = target.ID
+		req.TargetAddr = target.Addr
+		m.send(relayAddr, req)
+	}
+
+	select {
+	case <-ch:
+		return
+	case <-time.After(m.config.probeInterval - m.config.probeTimeout):
+	case <-m.done:
+		return
+	}
+
+	m.suspect(target.ID, target.Incarnation)
+}
+
+// reap forgets dead and left members that have not been updated for the reap timeout
+func (m *Memberlist) reap() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for id, since := range m.gone {
+		if now.Sub(since) < m.config.reapTimeout {
+			continue
+		}
+
+		delete(m.gone, id)
+		if member, ok := m.members[id]; ok && !member.IsActive() {
+			delete(m.members, id)
+		}
+	}
+}
+
+// nextProbeTarget returns the next active member in round-robin order
+// The order is reshuffled after every full round.
+func (m *Memberlist) nextProbeTarget() (Member, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for attempts := 0; attempts < 2; attempts++ {
+		for m.probeIndex < len(m.probeOrder) {
+			member, ok := m.members[m.probeOrder[m.probeIndex]]
+			m.probeIndex++
+			if ok && member.ID != m.selfID && member.IsActive() {
+				return copyMember(member), true
+			}
+		}
+
+		m.probeOrder = m.probeOrder[:0]
+		for id := range m.members {
+			m.probeOrder = append(m.probeOrder, id)
+		}
+		rand.Shuffle(len(m.probeOrder), func(i, j int) {
+			m.probeOrder[i], m.probeOrder[j] = m.probeOrder[j], m.probeOrder[i]
+		})
+		m.probeIndex = 0
+	}
+
+	return Member{}, false
+}
+
+// randomMembers returns up to n random alive members other than this one and exclude
+func (m *Memberlist) randomMembers(n int, exclude string) []Member {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	candidates := make([]Member, 0, len(m.members))
+	for id, member := range m.members {
+		if id != m.selfID && id != exclude && member.State == StateAlive {
+			candidates = append(candidates, copyMember(member))
+		}
+	}
+
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	return candidates
+}
+
+// suspect marks a member suspect at the given incarnation and gossips it
+func (m *Memberlist) suspect(id string, incarnation uint64) {
+	m.mu.Lock()
+	member, ok := m.members[id]
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+	update := copyMember(member)
+	m.mu.Unlock()
+
+	update.State = StateSuspect
+	update.Incarnation = incarnation
+	m.applyUpdates([]Member{update})
+}
+
+// applyUpdates merges membership updates into the member list and emits events
+func (m *Memberlist) applyUpdates(updates []Member) {
+	if len(updates) == 0 {
+		return
+	}
+
+	events := make([]Event, 0)
+
+	m.mu.Lock()
+	for i := range updates {
+		if event, ok := m.applyUpdate(updates[i]); ok {
+			events = append(events, event)
+		}
+	}
+	m.mu.Unlock()
+
+	m.eventMu.RLock()
+	defer m.eventMu.RUnlock()
+
+	if m.closed {
+		return
+	}
+
+	for _, event := range events {
+		select {
+		case m.events <- event:
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// applyUpdate merges one update; the caller must hold the lock
+func (m *Memberlist) applyUpdate(update Member) (Event, bool) {
+	if update.ID == "" {
+		return Event{}, false
+	}
+
+	// Refute suspicion about ourselves with a higher incarnation
+	if update.ID == m.selfID {
+		self := m.members[m.selfID]
+		if self.State == StateLeft {
+			return Event{}, false
+		}
+		if (update.State == StateSuspect || update.State == StateDead) && update.Incarnation >= self.Incarnation {
+			self.Incarnation = update.Incarnation + 1
+			m.enqueueBroadcast(copyMember(self))
+		}
+		return Event{}, false
+	}
+
+	known, exists := m.members[update.ID]
+	if exists && !update.supersedes(known) {
+		return Event{}, false
+	}
+
+	previous := State("")
+	if exists {
+		previous = known.State
+	}
+
+	updated := copyMember(&update)
+	m.members[update.ID] = &updated
+
+	// Departures of members we never knew are remembered but not passed on,
+	// so members that already reaped them do not learn about them again
+	if exists || updated.IsActive() {
+		m.enqueueBroadcast(copyMember(&updated))
+	}
+
+	if updated.IsActive() {
+		delete(m.gone, update.ID)
+	} else {
+		m.gone[update.ID] = time.Now()
+	}
+
+	// Suspects are declared dead unless they refute in time
+	if timer, ok := m.suspects[update.ID]; ok && updated.State != StateSuspect {
+		timer.Stop()
+		delete(m.suspects, update.ID)
+	}
+	if updated.State == StateSuspect && previous != StateSuspect {
+		m.startSuspicionTimer(updated.ID, updated.Incarnation)
+	}
+
+	event := Event{Member: copyMember(&updated), Previous: previous}
+	switch {
+	case updated.State == previous:
+		// Same state at a higher incarnation, e.g. a refuted suspicion was
+		// overtaken or metadata changed; nothing to report
+		return Event{}, false
+	case previous == "" && !updated.IsActive():
+		// Members that were gone before we heard of them never joined from our view
+		return Event{}, false
+	case updated.IsActive() && (previous == "" || previous == StateLeft):
+		event.Type = EventJoin
+	case updated.State == StateAlive:
+		event.Type = EventAlive
+	case updated.State == StateSuspect:
+		event.Type = EventSuspect
+	case updated.State == StateDead:
+		event.Type = EventDead
+	case updated.State == StateLeft:
+		event.Type = EventLeave
+	default:
+		return Event{}, false
+	}
+
+	return event, true
+}
+
+// startSuspicionTimer declares a suspect dead after the suspicion timeout
+// The caller must hold the lock.
+func (m *Memberlist) startSuspicionTimer(id string, incarnation uint64) {
+	if m.shutdown {
+		return
+	}
+
+	m.suspects[id] = time.AfterFunc(m.config.suspicionTimeout, func() {
+		m.mu.Lock()
+		member, ok := m.members[id]
+		if !ok || member.State != StateSuspect || member.Incarnation != incarnation {
+			m.mu.Unlock()
+			return
+		}
+		delete(m.suspects, id)
+		update := copyMember(member)
+		m.mu.Unlock()
+
+		update.State = StateDead
+		m.applyUpdates([]Member{update})
+	})
+}
+
+// enqueueBroadcast queues an update for piggybacking, replacing older updates
+// about the same member; the caller must hold the lock
+func (m *Memberlist) enqueueBroadcast(update Member) {
+	for i, queued := range m.broadcasts {
+		if queued.update.ID == update.ID {
+			m.broadcasts = append(m.broadcasts[:i], m.broadcasts[i+1:]...)
+			break
+		}
+	}
+	m.broadcasts = append(m.broadcasts, &broadcast{update: update})
+}
+
+// piggyback returns the updates to attach to an outgoing message
+// Updates are dropped once they have been sent retransmitMult * log10(n+1) times.
+func (m *Memberlist) piggyback() []Member {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	limit := m.config.retransmitMult * int(math.Ceil(math.Log10(float64(len(m.members)+1))))
+
+	updates := make([]Member, 0, min(len(m.broadcasts), maxPiggyback))
+	kept := m.broadcasts[:0]
+	for _, queued := range m.broadcasts {
+		if len(updates) < maxPiggyback {
+			updates = append(updates, queued.update)
+			queued.transmits++
+		}
+		if queued.transmits < limit {
+			kept = append(kept, queued)
+		}
+	}
+	m.broadcasts = kept
+
+	return updates
+}
+
+// newMessage creates a message from this member with piggybacked updates
+func (m *Memberlist) newMessage(kind messageType, seq uint64) *message {
+	return &message{
+		Type:    kind,
+		Seq:     seq,
+		From:    m.selfID,
+		Updates: m.piggyback(),
+	}
+}
+
+// send writes a message to the given address, logging failures
+func (m *Memberlist) send(addr *net.UDPAddr, msg *message) {
+	data, err := msg.encode()
+	if err != nil {
+		fmt.Printf("error encoding gossip message: %s\n", err)
+		return
+	}
+
+	if len(data) > maxPacketSize {
+		fmt.Printf("gossip message to %s too large: %d bytes\n", addr, len(data))
+		return
+	}
+
+	if _, err := m.conn.WriteToUDP(data, addr); err != nil {
+		select {
+		case <-m.done:
+		default:
+			fmt.Printf("error sending gossip message to %s: %s\n", addr, err)
+		}
+	}
+}
+
+// expectAck registers a new sequence number and the channel its answer is delivered on
+// Only messages of the given kind from one of the given senders are accepted;
+// without senders any member may answer.
+func (m *Memberlist) expectAck(kind messageType, senders ...string) (uint64, chan *message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.shutdown {
+		return 0, nil, ErrShutdown
+	}
+
+	m.seq++
+	pending := &pendingAck{
+		ch:   make(chan *message, 1),
+		kind: kind,
+	}
+	if len(senders) > 0 {
+		pending.senders = make(map[string]struct{}, len(senders))
+		for _, sender := range senders {
+			pending.senders[sender] = struct{}{}
+		}
+	}
+	m.acks[m.seq] = pending
+	return m.seq, pending.ch, nil
+}
+
+// acceptAckFrom also accepts the answer to a sequence number from the given sender
+func (m *Memberlist) acceptAckFrom(seq uint64, sender string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if pending, ok := m.acks[seq]; ok && pending.senders != nil {
+		pending.senders[sender] = struct{}{}
+	}
+}
+
+// forgetAck stops waiting for the ack of a sequence number
+func (m *Memberlist) forgetAck(seq uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.acks, seq)
+}
+
+// copyMember returns a copy of a member that shares nothing with it
+func copyMember(member *Member) Member {
+	copied := *member
+	if member.Meta != nil {
+		copied.Meta = make(map[string]string, len(member.Meta))
+		for key, value := range member.Meta {
+			copied.Meta[key] = value
+		}
+	}
+	return copied
+}
@@ -0,0 +1,200 @@
+package swim
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testOptions speed the protocol up so failures are detected within a test
+var testOptions = []Option{
+	WithProbeInterval(50*time.Millisecond, 20*time.Millisecond),
+	WithSuspicionTimeout(200 * time.Millisecond),
+	WithEventBuffer(1024),
+}
+
+// newTestMember starts a memberlist on a free loopback port
+// Its events are drained in the background and it is shut down when the test ends.
+func newTestMember(t *testing.T, id string, opts ...Option) *Memberlist {
+	t.Helper()
+
+	m, err := New(id, "127.0.0.1:0", append(append([]Option{}, testOptions...), opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for range m.Events() {
+		}
+	}()
+	t.Cleanup(func() { m.Shutdown() })
+
+	return m
+}
+
+// memberState returns the state m knows other is in, empty if m does not know it
+func memberState(m *Memberlist, id string) State {
+	for _, member := range m.Members() {
+		if member.ID == id {
+			return member.State
+		}
+	}
+	return ""
+}
+
+// waitFor polls cond until it holds or the timeout expires
+func waitFor(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMembersDetectFailureAndReap(t *testing.T) {
+	reap := WithReapTimeout(300 * time.Millisecond)
+	a := newTestMember(t, "a", reap)
+	b := newTestMember(t, "b", reap)
+	c := newTestMember(t, "c", reap)
+
+	for _, m := range []*Memberlist{b, c} {
+		if _, err := m.Join([]string{a.LocalMember().Addr}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waitFor(t, 2*time.Second, "all members to be alive everywhere", func() bool {
+		for _, m := range []*Memberlist{a, b, c} {
+			for _, id := range []string{"a", "b", "c"} {
+				if memberState(m, id) != StateAlive {
+					return false
+				}
+			}
+		}
+		return true
+	})
+
+	c.Shutdown()
+
+	waitFor(t, 3*time.Second, "c to be declared dead", func() bool {
+		return memberState(a, "c") == StateDead && memberState(b, "c") == StateDead
+	})
+
+	waitFor(t, 3*time.Second, "c to be reaped", func() bool {
+		return memberState(a, "c") == "" && memberState(b, "c") == ""
+	})
+
+	if memberState(a, "b") != StateAlive || memberState(b, "a") != StateAlive {
+		t.Error("reaping affected alive members")
+	}
+}
+
+func TestJoinReceivesLargeMemberList(t *testing.T) {
+	seed := newTestMember(t, "seed")
+
+	// Far more departed members than fit in one datagram
+	const departed = 1000
+	seed.mu.Lock()
+	for i := 0; i < departed; i++ {
+		id := fmt.Sprintf("departed-%d", i)
+		seed.members[id] = &Member{
+			ID:          id,
+			Addr:        "127.0.0.1:1",
+			State:       StateLeft,
+			Incarnation: 1,
+			Meta:        map[string]string{"zone": strings.Repeat("z", 64)},
+		}
+		seed.gone[id] = time.Now()
+	}
+	seed.mu.Unlock()
+
+	// Join waits one probe interval for the sync
+	joiner := newTestMember(t, "joiner", WithProbeInterval(time.Second, 0))
+	if _, err := joiner.Join([]string{seed.LocalMember().Addr}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, 2*time.Second, "the joiner to learn every member", func() bool {
+		return len(joiner.Members()) == departed+2
+	})
+}
+
+func TestAcksAreMatchedToTheirSender(t *testing.T) {
+	m := newTestMember(t, "a")
+	from := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}
+
+	seq, ch, err := m.expectAck(msgAck, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.forgetAck(seq)
+
+	// An ack with the right sequence number from another member, or a sync, is ignored
+	m.handle(&message{Type: msgAck, Seq: seq, From: "c"}, from)
+	m.handle(&message{Type: msgSync, Seq: seq, From: "b"}, from)
+	select {
+	case msg := <-ch:
+		t.Fatalf("accepted a %s from %s", msg.Type, msg.From)
+	default:
+	}
+
+	// Relays probing the target indirectly may answer too
+	m.acceptAckFrom(seq, "c")
+	m.handle(&message{Type: msgAck, Seq: seq, From: "c"}, from)
+	select {
+	case <-ch:
+	default:
+		t.Fatal("ack from the relay was ignored")
+	}
+}
+
+func TestShutdownWaitsForRelayedPings(t *testing.T) {
+	a := newTestMember(t, "a")
+	b := newTestMember(t, "b")
+
+	// a relays a ping to a target that never answers
+	a.handle(&message{Type: msgPingReq, Seq: 1, From: "b", Target: "x", TargetAddr: "127.0.0.1:1"}, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1})
+
+	done := make(chan struct{})
+	go func() {
+		a.Shutdown()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("shutdown did not return")
+	}
+
+	b.Shutdown()
+}
+
+func TestAcksRaceWithAddedRelays(t *testing.T) {
+	m := newTestMember(t, "a")
+	from := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}
+
+	seq, _, err := m.expectAck(msgAck, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.forgetAck(seq)
+
+	// Late direct acks arrive while relays are being added
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			m.acceptAckFrom(seq, fmt.Sprintf("relay-%d", i))
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		m.handle(&message{Type: msgAck, Seq: seq, From: "b"}, from)
+	}
+	<-done
+}
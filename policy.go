@@ -0,0 +1,56 @@
+package pantheon
+
+import "time"
+
+// HeartbeatPolicy overrides the cluster's heartbeat settings for one node
+// Zero fields fall back to the cluster options. The probe type is selected by
+// JoinOp.HealthCheck.
+type HeartbeatPolicy struct {
+	// Interval; how often the node is checked
+	Interval time.Duration
+	// Timeout; how long a check of the node may take
+	Timeout time.Duration
+	// MaxFailures; the number of failed checks before the node is considered dead
+	MaxFailures int
+}
+
+// validate checks that no field of the policy is negative and, if the policy
+// sets its interval or timeout, that a check ends before the next one is due
+// The timeout is compared with the interval after filling in the defaults, so
+// overriding only one of them is checked against the cluster's other value.
+func (p HeartbeatPolicy) validate(defaults HeartbeatPolicy) error {
+	if p.Interval < 0 || p.Timeout < 0 || p.MaxFailures < 0 {
+		return ErrInvalidHeartbeatPolicy
+	}
+
+	if p.Interval == 0 && p.Timeout == 0 {
+		return nil
+	}
+
+	interval, timeout := p.Interval, p.Timeout
+	if interval == 0 {
+		interval = defaults.Interval
+	}
+	if timeout == 0 {
+		timeout = defaults.Timeout
+	}
+	if timeout >= interval {
+		return ErrInvalidHeartbeatPolicyTimeout
+	}
+	return nil
+}
+
+// heartbeatPolicy returns the node's heartbeat policy with the cluster defaults filled in
+func (c *Pantheon) heartbeatPolicy(node *Member) HeartbeatPolicy {
+	policy := node.Heartbeat
+	if policy.Interval == 0 {
+		policy.Interval = c.heartbeatInterval
+	}
+	if policy.Timeout == 0 {
+		policy.Timeout = c.heartbeatTimeout
+	}
+	if policy.MaxFailures == 0 {
+		policy.MaxFailures = c.heartbeatMaxFailures
+	}
+	return policy
+}
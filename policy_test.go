@@ -0,0 +1,34 @@
+package pantheon
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatPolicyValidate(t *testing.T) {
+	defaults := HeartbeatPolicy{Interval: 30 * time.Second, Timeout: 30 * time.Second, MaxFailures: 5}
+
+	tests := []struct {
+		name   string
+		policy HeartbeatPolicy
+		want   error
+	}{
+		{name: "cluster defaults", policy: HeartbeatPolicy{}},
+		{name: "max failures only", policy: HeartbeatPolicy{MaxFailures: 3}},
+		{name: "timeout below interval", policy: HeartbeatPolicy{Interval: time.Second, Timeout: 500 * time.Millisecond}},
+		{name: "negative field", policy: HeartbeatPolicy{Interval: -time.Second}, want: ErrInvalidHeartbeatPolicy},
+		{name: "timeout equals interval", policy: HeartbeatPolicy{Interval: time.Second, Timeout: time.Second}, want: ErrInvalidHeartbeatPolicyTimeout},
+		{name: "timeout above interval", policy: HeartbeatPolicy{Interval: time.Second, Timeout: time.Minute}, want: ErrInvalidHeartbeatPolicyTimeout},
+		{name: "interval below default timeout", policy: HeartbeatPolicy{Interval: 10 * time.Second}, want: ErrInvalidHeartbeatPolicyTimeout},
+		{name: "timeout below default interval", policy: HeartbeatPolicy{Timeout: 10 * time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.validate(defaults); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
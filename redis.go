@@ -22,6 +22,18 @@ type RedisClient interface {
 	Get(ctx context.Context, key string) *redis.StringCmd
 	SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
 	SMembers(ctx context.Context, key string) *redis.StringSliceCmd
+	SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SCard(ctx context.Context, key string) *redis.IntCmd
+	SRandMemberN(ctx context.Context, key string, count int64) *redis.StringSliceCmd
+	// Added for replica sets
+	RPush(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
+	LRange(ctx context.Context, key string, start, stop int64) *redis.StringSliceCmd
+	LTrim(ctx context.Context, key string, start, stop int64) *redis.StatusCmd
+	// Added for batch lookups
+	MGet(ctx context.Context, keys ...string) *redis.SliceCmd
+	Pipeline() redis.Pipeliner
+	// Added for epoch-checked ring saves
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
 }
 
 type RedisClientOptions struct {
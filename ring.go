@@ -0,0 +1,196 @@
+package pantheon
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fleetcontrolsio/pantheon/pkg/hashring"
+)
+
+// ringSaveAttempts is the number of times SaveRing retries after losing a race with another process
+const ringSaveAttempts = 5
+
+// persistentRing is implemented by rings whose state can be saved and restored
+type persistentRing interface {
+	State() (*hashring.State, error)
+	Restore(state *hashring.State) error
+	Epoch() uint64
+}
+
+// SaveRing persists the state of the hash ring in Redis
+// Other Pantheon processes can then load the exact same ring with LoadRing.
+// The ring is only stored if its epoch is newer than the stored one. If another
+// process stored a newer ring first, the stored ring is loaded, the cluster
+// members in Redis are applied to it and the save is retried, so concurrent
+// changes from several processes are merged rather than overwritten.
+// Rings that cannot be serialized (custom hashring.Ring implementations) are skipped.
+func (c *Pantheon) SaveRing() error {
+	ring, ok := c.hashRing.(persistentRing)
+	if !ok {
+		return nil
+	}
+
+	for attempt := 0; attempt < ringSaveAttempts; attempt++ {
+		state, err := ring.State()
+		if err != nil {
+			return fmt.Errorf("error serializing hash ring: %w", err)
+		}
+
+		data, err := state.MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("error serializing hash ring: %w", err)
+		}
+
+		saved, err := c.storage.SaveRing(c.ctx, data, state.Epoch)
+		if err != nil {
+			return err
+		}
+
+		if saved {
+			return nil
+		}
+
+		// Another process saved a ring at the same or a later epoch
+		if err := c.LoadRing(); err != nil {
+			return err
+		}
+
+		changed, err := c.reconcileRing()
+		if err != nil {
+			return err
+		}
+
+		// The stored ring already reflects every member
+		if !changed {
+			return nil
+		}
+	}
+
+	return ErrRingConflict
+}
+
+// LoadRing replaces the local hash ring with the state persisted in Redis
+// It does nothing if no ring has been saved yet, and keeps the local ring if
+// it is newer than the persisted one.
+func (c *Pantheon) LoadRing() error {
+	ring, ok := c.hashRing.(persistentRing)
+	if !ok {
+		return nil
+	}
+
+	data, err := c.storage.GetRing(c.ctx)
+	if err != nil {
+		return err
+	}
+
+	if data == nil {
+		return nil
+	}
+
+	var state hashring.State
+	if err := state.UnmarshalBinary(data); err != nil {
+		return fmt.Errorf("error restoring hash ring: %w", err)
+	}
+
+	if err := ring.Restore(&state); err != nil && !errors.Is(err, hashring.ErrStaleEpoch) {
+		return fmt.Errorf("error restoring hash ring: %w", err)
+	}
+
+	return nil
+}
+
+// refreshRing loads the ring persisted in Redis if another process saved a newer one
+func (c *Pantheon) refreshRing() error {
+	ring, ok := c.hashRing.(persistentRing)
+	if !ok {
+		return nil
+	}
+
+	epoch, err := c.storage.GetRingEpoch(c.ctx)
+	if err != nil {
+		return err
+	}
+
+	if epoch <= ring.Epoch() {
+		return nil
+	}
+
+	return c.LoadRing()
+}
+
+// reconcileRing applies the cluster members stored in Redis to the hash ring
+// Members missing from the ring are added, nodes that are no longer members are
+// removed and the address, status, weight and topology of the others updated.
+// It reports whether the ring changed.
+func (c *Pantheon) reconcileRing() (bool, error) {
+	members, err := c.storage.GetNodes(c.ctx)
+	if err != nil {
+		return false, err
+	}
+
+	current := make(map[string]*hashring.Node)
+	for _, node := range c.hashRing.GetNodes() {
+		current[node.ID] = node
+	}
+
+	changed := false
+	for _, member := range members {
+		desired := &hashring.Node{
+			ID:       member.ID,
+			Address:  member.Address,
+			Status:   memberRingStatus(member.State),
+			Weight:   member.Weight,
+			Topology: member.Topology,
+		}
+
+		existing, ok := current[member.ID]
+		delete(current, member.ID)
+
+		switch {
+		case !ok:
+			err = c.hashRing.AddNode(desired)
+		case existing.Address != desired.Address || existing.Status != desired.Status ||
+			existing.EffectiveWeight() != desired.EffectiveWeight() || existing.Topology != desired.Topology:
+			err = c.hashRing.UpdateNode(desired)
+		default:
+			continue
+		}
+
+		if err != nil {
+			return changed, err
+		}
+		changed = true
+	}
+
+	for id := range current {
+		if err := c.hashRing.RemoveNode(id); err != nil {
+			return changed, err
+		}
+		changed = true
+	}
+
+	return changed, nil
+}
+
+// memberRingStatus returns the hash ring status of a member in the given state
+// Suspect nodes keep their keys, so they stay active.
+func memberRingStatus(state MemberState) hashring.NodeStatus {
+	if status, ok := ringStatus(state); ok {
+		return status
+	}
+	return hashring.NodeStatusActive
+}
+
+// saveRing persists the hash ring after a change, logging failures
+// In partition mode the partition table is rebalanced against the new ring.
+func (c *Pantheon) saveRing() {
+	if err := c.SaveRing(); err != nil {
+		fmt.Printf("error saving hash ring: %s\n", err)
+	}
+
+	if c.partitioner != nil {
+		if err := c.RebalancePartitions(); err != nil {
+			fmt.Printf("error rebalancing partitions: %s\n", err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package pantheon
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/fleetcontrolsio/pantheon/pkg/hashring"
+)
+
+// ringNodes returns the nodes of a ring by ID
+func ringNodes(ring hashring.Ring) map[string]*hashring.Node {
+	nodes := make(map[string]*hashring.Node)
+	for _, node := range ring.GetNodes() {
+		nodes[node.ID] = node
+	}
+	return nodes
+}
+
+func TestSaveRingMergesConcurrentChanges(t *testing.T) {
+	server := miniredis.RunT(t)
+	a := newTestPantheon(t, server)
+	b := newTestPantheon(t, server)
+
+	// Both processes start from the same epoch and change the ring independently
+	if err := a.Join(&JoinOp{ID: "node-a", Address: "10.0.0.1", Port: 80, HealthCheck: HealthCheckPush}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Join(&JoinOp{ID: "node-b", Address: "10.0.0.2", Port: 80, HealthCheck: HealthCheckPush}); err != nil {
+		t.Fatal(err)
+	}
+
+	if nodes := ringNodes(b.hashRing); nodes["node-a"] == nil || nodes["node-b"] == nil {
+		t.Fatalf("b did not merge a's change: %v", nodes)
+	}
+
+	// a picks up b's ring on its next heartbeat tick
+	if err := a.refreshRing(); err != nil {
+		t.Fatal(err)
+	}
+	if nodes := ringNodes(a.hashRing); nodes["node-a"] == nil || nodes["node-b"] == nil {
+		t.Fatalf("a did not load b's ring: %v", nodes)
+	}
+
+	// A stale ring can no longer overwrite a newer one
+	stale := hashring.NewHashRing(10)
+	state, err := stale.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := state.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := a.storage.SaveRing(a.ctx, data, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved {
+		t.Fatal("stale ring overwrote the stored ring")
+	}
+
+	epoch, err := a.storage.GetRingEpoch(a.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if epoch != a.hashRing.(*hashring.HashRing).Epoch() {
+		t.Fatalf("stored epoch %d, local epoch %d", epoch, a.hashRing.(*hashring.HashRing).Epoch())
+	}
+}
+
+func TestJoinRefreshesAddressAndTopology(t *testing.T) {
+	server := miniredis.RunT(t)
+	p := newTestPantheon(t, server)
+
+	if err := p.Join(&JoinOp{ID: "node-1", Address: "10.0.0.1", Port: 80, HealthCheck: HealthCheckPush,
+		Topology: hashring.Topology{Zone: "a"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Join(&JoinOp{ID: "node-1", Address: "10.0.0.9", Port: 81, HealthCheck: HealthCheckPush,
+		Topology: hashring.Topology{Zone: "b"}, Weight: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	node := ringNodes(p.hashRing)["node-1"]
+	if node.Address != "10.0.0.9:81" || node.Topology.Zone != "b" || node.Weight != 2 {
+		t.Fatalf("rejoined node not refreshed: %+v", node)
+	}
+}
+
+func TestRejoinKeepsStoredState(t *testing.T) {
+	server := miniredis.RunT(t)
+	p := newTestPantheon(t, server, func(o *Options) {
+		// The immediate ping after the rejoin must not revive the node
+		o.WithReviveSuccesses(5)
+	})
+	joinTestNodes(t, p, "node-a", "node-b")
+
+	setStoredNodeState(t, p, "node-a", MemberDead)
+	if err := p.Join(&JoinOp{ID: "node-a", Address: "10.0.0.1", Port: 80, HealthCheck: HealthCheckPush}); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := p.GetNodeHealth("node-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := ringNodes(p.hashRing)["node-a"]
+	if state != MemberDead || node.Status != hashring.NodeStatusInactive {
+		t.Fatalf("rejoined dead node is %s in Redis and %s in the ring", state, node.Status)
+	}
+
+	for i := 0; i < 100; i++ {
+		owner, err := p.hashRing.GetNode(fmt.Sprintf("key-%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if owner.ID == "node-a" {
+			t.Fatal("keys route to the dead node after it rejoined")
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package pantheon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fleetcontrolsio/pantheon/pkg/hashring"
+)
+
+// memberTransitions lists the states each state may move to
+// Left is final: a node that left has to join again. Dead and maintenance
+// nodes move to draining instead of alive if they were draining before.
+var memberTransitions = map[MemberState][]MemberState{
+	MemberAlive:       {MemberSuspect, MemberDead, MemberDraining, MemberMaintenance, MemberLeft},
+	MemberSuspect:     {MemberAlive, MemberDead, MemberMaintenance, MemberLeft},
+	MemberDead:        {MemberAlive, MemberDraining, MemberMaintenance, MemberLeft},
+	MemberDraining:    {MemberAlive, MemberDead, MemberLeft},
+	MemberMaintenance: {MemberAlive, MemberDraining, MemberLeft},
+	MemberLeft:        {},
+}
+
+// CanTransition reports whether a node may move from one state to another
+func CanTransition(from, to MemberState) bool {
+	for _, state := range memberTransitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StateMachine decides the next state of a node from its heartbeats
+type StateMachine struct {
+	// SuspicionTimeout; how long a node may stay suspect before it is considered dead
+	// 0 uses the node's heartbeat interval times its max failures
+	SuspicionTimeout time.Duration
+	// ReviveSuccesses; the number of consecutive successful heartbeats a dead node needs to be revived
+	ReviveSuccesses int
+}
+
+// Next returns the state a node moves to after a heartbeat
+// detected is the state suggested by the failure detector and successes the
+// number of consecutive successful heartbeats of a dead node, including this one.
+//   - alive nodes take the detected state
+//   - suspect nodes are refuted by a single success and die once the detector
+//     says so or the suspicion timeout has passed
+//   - dead nodes are revived after ReviveSuccesses consecutive successes
+//   - draining nodes only die; maintenance and left nodes do not change
+func (m StateMachine) Next(node *Member, policy HeartbeatPolicy, detected MemberState, successes int, now time.Time) MemberState {
+	switch node.State {
+	case MemberAlive:
+		return detected
+	case MemberSuspect:
+		if detected == MemberAlive || detected == MemberDead {
+			return detected
+		}
+
+		timeout := m.SuspicionTimeout
+		if timeout == 0 {
+			timeout = policy.Interval * time.Duration(policy.MaxFailures)
+		}
+
+		if !node.StateSince.IsZero() && now.Sub(node.StateSince) >= timeout {
+			return MemberDead
+		}
+		return MemberSuspect
+	case MemberDead:
+		if detected == MemberAlive && successes >= m.ReviveSuccesses {
+			return MemberAlive
+		}
+		return MemberDead
+	case MemberDraining:
+		if detected == MemberDead {
+			return MemberDead
+		}
+		return MemberDraining
+	default:
+		return node.State
+	}
+}
+
+// setNodeState moves a node to a new state
+// The transition is validated, stored and mirrored in the hash ring, and the
+// matching event is sent. Moving a node to the state it is in does nothing.
+// A node that was draining when it died or went into maintenance resumes
+// draining instead of becoming alive, so the drain still completes. Dead nodes
+// lose their degraded flag without a "recovered" event.
+func (c *Pantheon) setNodeState(node *Member, state MemberState) error {
+	if state == MemberAlive && node.DrainRequested {
+		state = MemberDraining
+	}
+
+	if node.State == state {
+		return nil
+	}
+
+	if !CanTransition(node.State, state) {
+		return fmt.Errorf("%w: node %s from %s to %s", ErrInvalidTransition, node.ID, node.State, state)
+	}
+
+	if err := c.storage.UpdateNodeState(c.ctx, node.ID, node.State, state); err != nil {
+		return fmt.Errorf("error updating node state: %w", err)
+	}
+
+	// Suspect nodes keep their keys until they are considered dead
+	if status, ok := ringStatus(state); ok && c.hashRing != nil {
+		err := c.hashRing.UpdateNodeStatus(node.ID, status)
+		if err != nil && err != hashring.ErrNodeNotFound {
+			fmt.Printf("error updating node status in hash ring: %s\n", err)
+		} else if err == nil {
+			c.saveRing()
+		}
+	}
+
+	var event string
+	switch state {
+	case MemberAlive:
+		event = "revived"
+		switch node.State {
+		case MemberMaintenance:
+			event = "maintenance_ended"
+		case MemberDraining:
+			event = "undrained"
+		}
+	case MemberDead:
+		event = "died"
+		go c.redistributeNodeKeys(node.ID)
+	case MemberDraining:
+		event = "draining"
+		// Drain starts the migration itself
+		if node.DrainRequested {
+			go c.migrateDrainingNode(node.ID)
+		}
+	case MemberMaintenance:
+		event = "maintenance"
+	}
+
+	if event != "" && c.EventsCh != nil {
+		c.EventsCh <- PantheonEvent{
+			Event:  event,
+			NodeID: node.ID,
+		}
+	}
+
+	fmt.Printf("Node %s is %s (was %s)\n", node.ID, state, node.State)
+	node.State = state
+	node.StateSince = time.Now()
+	if state == MemberDraining {
+		node.DrainRequested = true
+	}
+	if state == MemberDead {
+		node.Degraded = false
+	}
+	return nil
+}
+
+// ringStatus returns the hash ring status of a node in the given state
+// It returns false for states that leave the ring untouched.
+func ringStatus(state MemberState) (hashring.NodeStatus, bool) {
+	switch state {
+	case MemberAlive:
+		return hashring.NodeStatusActive, true
+	case MemberDead, MemberMaintenance:
+		return hashring.NodeStatusInactive, true
+	case MemberDraining:
+		return hashring.NodeStatusDraining, true
+	default:
+		return "", false
+	}
+}
+
+// redistributeNodeKeys moves the keys mapped to a dead node to the nodes now owning them
+func (c *Pantheon) redistributeNodeKeys(nodeID string) {
+	// Get all keys assigned to this node
+	nodeKeysKey := c.storage.makeKey("nodekeys", nodeID)
+	keys, err := c.storage.redis.SMembers(c.ctx, nodeKeysKey).Result()
+	if err != nil {
+		fmt.Printf("error getting keys for dead node: %s\n", err)
+		return
+	}
+
+	if len(keys) > 0 {
+		fmt.Printf("Redistributing %d keys from dead node %s\n", len(keys), nodeID)
+		if err := c.Distribute(keys); err != nil {
+			fmt.Printf("error redistributing keys: %s\n", err)
+		}
+	}
+}
+
+// StartMaintenance takes a node out of service for planned work
+// Its heartbeats are no longer checked and new keys no longer land on it, but
+// its key mappings are kept so they return to it when maintenance ends.
+func (c *Pantheon) StartMaintenance(id string) error {
+	if !c.started {
+		return fmt.Errorf("cluster not started")
+	}
+
+	node, err := c.storage.GetNode(c.ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if node == nil {
+		return fmt.Errorf("node %s not found", id)
+	}
+
+	return c.setNodeState(node, MemberMaintenance)
+}
+
+// EndMaintenance puts a node in maintenance back into service as alive
+// The next heartbeats decide whether it stays alive.
+func (c *Pantheon) EndMaintenance(id string) error {
+	if !c.started {
+		return fmt.Errorf("cluster not started")
+	}
+
+	node, err := c.storage.GetNode(c.ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if node == nil {
+		return fmt.Errorf("node %s not found", id)
+	}
+
+	if node.State != MemberMaintenance {
+		return fmt.Errorf("%w: node %s is %s, not in maintenance", ErrInvalidTransition, id, node.State)
+	}
+
+	return c.setNodeState(node, MemberAlive)
+}
@@ -0,0 +1,142 @@
+package pantheon
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// setStoredNodeState moves a node to a state, starting from its stored record
+func setStoredNodeState(t *testing.T, p *Pantheon, id string, state MemberState) *Member {
+	t.Helper()
+
+	node, err := p.storage.GetNode(p.ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.setNodeState(node, state); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := p.storage.GetNode(p.ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stored
+}
+
+func TestDrainIntentSurvivesDeath(t *testing.T) {
+	server := miniredis.RunT(t)
+	p := newTestPantheon(t, server)
+	joinTestNodes(t, p, "node-a", "node-b", "node-c")
+
+	if err := p.Drain("node-a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Draining -> dead -> alive resumes draining
+	setStoredNodeState(t, p, "node-a", MemberDead)
+	node := setStoredNodeState(t, p, "node-a", MemberAlive)
+	if node.State != MemberDraining || !node.DrainRequested {
+		t.Fatalf("revived draining node is %s (drain requested %t)", node.State, node.DrainRequested)
+	}
+
+	// Dead -> maintenance -> alive resumes draining too
+	setStoredNodeState(t, p, "node-a", MemberDead)
+	setStoredNodeState(t, p, "node-a", MemberMaintenance)
+	node = setStoredNodeState(t, p, "node-a", MemberAlive)
+	if node.State != MemberDraining {
+		t.Fatalf("node leaving maintenance is %s, want draining", node.State)
+	}
+
+	// Nodes that were never drained revive as usual
+	setStoredNodeState(t, p, "node-b", MemberDead)
+	if node := setStoredNodeState(t, p, "node-b", MemberAlive); node.State != MemberAlive {
+		t.Fatalf("revived node is %s, want alive", node.State)
+	}
+}
+
+// heartbeatCounts returns the stored failure and success counts of a node
+func heartbeatCounts(t *testing.T, p *Pantheon, id string) (string, string) {
+	t.Helper()
+
+	values, err := p.storage.redis.HGetAll(p.ctx, p.storage.makeKey("nodes", id)).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return values["heartbeat_failure_count"], values["heartbeat_success_count"]
+}
+
+func TestDrainingRevivalResetsHeartbeatCounts(t *testing.T) {
+	server := miniredis.RunT(t)
+	p := newTestPantheon(t, server, func(o *Options) {
+		o.WithReviveSuccesses(3)
+	})
+	joinTestNodes(t, p, "node-a", "node-b")
+
+	if err := p.Drain("node-a"); err != nil {
+		t.Fatal(err)
+	}
+
+	for cycle := 0; cycle < 2; cycle++ {
+		// The node fails its heartbeats and dies, then answers again
+		for i := 0; i < 6; i++ {
+			if _, err := p.storage.IncrementHeartbeatFailures(p.ctx, "node-a"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		setStoredNodeState(t, p, "node-a", MemberDead)
+		for i := 0; i < 3; i++ {
+			if _, err := p.storage.IncrementHeartbeatSuccesses(p.ctx, "node-a"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		node := setStoredNodeState(t, p, "node-a", MemberAlive)
+		if node.State != MemberDraining {
+			t.Fatalf("cycle %d: revived node is %s, want draining", cycle, node.State)
+		}
+		if failures, successes := heartbeatCounts(t, p, "node-a"); failures != "0" || successes != "0" {
+			t.Fatalf("cycle %d: counts not reset after revival: failures %s, successes %s", cycle, failures, successes)
+		}
+	}
+}
+
+func TestUndrainCancelsDrainIntent(t *testing.T) {
+	server := miniredis.RunT(t)
+	p := newTestPantheon(t, server)
+	joinTestNodes(t, p, "node-a", "node-b")
+
+	if err := p.Drain("node-a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Undrain("node-a"); err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := p.GetMember("node-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.State != MemberAlive || node.DrainRequested {
+		t.Fatalf("undrained node is %s (drain requested %t)", node.State, node.DrainRequested)
+	}
+
+	// Undrained nodes revive as alive
+	setStoredNodeState(t, p, "node-a", MemberDead)
+	if node := setStoredNodeState(t, p, "node-a", MemberAlive); node.State != MemberAlive {
+		t.Fatalf("revived undrained node is %s, want alive", node.State)
+	}
+
+	// A dead node's drain request can be cancelled before it comes back
+	if err := p.Drain("node-a"); err != nil {
+		t.Fatal(err)
+	}
+	setStoredNodeState(t, p, "node-a", MemberDead)
+	if err := p.Undrain("node-a"); err != nil {
+		t.Fatal(err)
+	}
+	if node := setStoredNodeState(t, p, "node-a", MemberAlive); node.State != MemberAlive {
+		t.Fatalf("revived node is %s after its drain was cancelled", node.State)
+	}
+}
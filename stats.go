@@ -0,0 +1,177 @@
+package pantheon
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// degradedMinProbes is the number of successful probes a node needs in its
+// history before it can be considered degraded
+const degradedMinProbes = 5
+
+// NodeStats summarises a node's heartbeat history
+// Latencies and success rates cover direct probes only; indirect probes
+// measure the path from another node.
+type NodeStats struct {
+	// Probes; the number of direct probes in the history
+	Probes int
+	// P50, P95, P99; percentiles of the round-trip time of successful probes,
+	// zero for push-based nodes whose probes have no round-trip time
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+	// SuccessRates; the share of successful probes in each stats window
+	SuccessRates []SuccessRate
+	// LastError; the error of the most recent failed probe, direct or indirect
+	LastError string
+	// LastErrorAt; when the most recent failed probe completed
+	LastErrorAt time.Time
+}
+
+// SuccessRate is the share of successful probes within a window
+type SuccessRate struct {
+	// Window; how far back the rate looks
+	Window time.Duration
+	// Probes; the number of probes in the window
+	Probes int
+	// Rate; the share of successful probes between 0 and 1, 0 without probes
+	Rate float64
+}
+
+// GetNodeStats returns latency percentiles, success rates and the last error of a node
+// The statistics cover the heartbeat history only, so windows longer than the
+// history reaches back see fewer probes.
+func (c *Pantheon) GetNodeStats(nodeID string) (*NodeStats, error) {
+	node, err := c.storage.GetNode(c.ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if node == nil {
+		return nil, fmt.Errorf("node %s not found", nodeID)
+	}
+
+	history, err := c.storage.GetProbeHistory(c.ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newNodeStats(history, c.statsWindows, time.Now()), nil
+}
+
+// newNodeStats computes the statistics of a heartbeat history at the given time
+func newNodeStats(history []ProbeRecord, windows []time.Duration, now time.Time) *NodeStats {
+	stats := &NodeStats{
+		SuccessRates: make([]SuccessRate, len(windows)),
+	}
+	for i, window := range windows {
+		stats.SuccessRates[i].Window = window
+	}
+
+	rtts := make([]time.Duration, 0, len(history))
+	successes := make([]int, len(windows))
+
+	for _, record := range history {
+		if !record.Success && !record.At.Before(stats.LastErrorAt) {
+			stats.LastError = record.Error
+			stats.LastErrorAt = record.At
+		}
+
+		if record.Via != "" {
+			continue
+		}
+
+		stats.Probes++
+		if record.Success && record.RTT > 0 {
+			rtts = append(rtts, record.RTT)
+		}
+
+		for i, window := range windows {
+			if now.Sub(record.At) > window {
+				continue
+			}
+			stats.SuccessRates[i].Probes++
+			if record.Success {
+				successes[i]++
+			}
+		}
+	}
+
+	for i := range stats.SuccessRates {
+		if stats.SuccessRates[i].Probes > 0 {
+			stats.SuccessRates[i].Rate = float64(successes[i]) / float64(stats.SuccessRates[i].Probes)
+		}
+	}
+
+	slices.Sort(rtts)
+	stats.P50 = percentile(rtts, 50)
+	stats.P95 = percentile(rtts, 95)
+	stats.P99 = percentile(rtts, 99)
+
+	return stats
+}
+
+// percentile returns the nearest-rank percentile p of sorted values, 0 if there are none
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// checkDegraded sends a "degraded" event when the p95 latency of an alive node
+// reaches the degraded threshold, and a "recovered" event once it drops below
+// Only probes since the node became alive count, so a revived node is not
+// flagged again for latencies measured before it died.
+func (c *Pantheon) checkDegraded(node *Member) {
+	history, err := c.storage.GetProbeHistory(c.ctx, node.ID)
+	if err != nil {
+		fmt.Printf("error getting probe history of node %s: %s\n", node.ID, err)
+		return
+	}
+
+	history = slices.DeleteFunc(history, func(record ProbeRecord) bool {
+		return record.At.Before(node.StateSince)
+	})
+
+	successful := 0
+	for _, record := range history {
+		if record.Via == "" && record.Success && record.RTT > 0 {
+			successful++
+		}
+	}
+
+	if successful < degradedMinProbes {
+		return
+	}
+
+	stats := newNodeStats(history, nil, time.Now())
+	degraded := stats.P95 >= c.degradedLatency
+	if degraded == node.Degraded {
+		return
+	}
+
+	if err := c.storage.UpdateNodeDegraded(c.ctx, node.ID, degraded); err != nil {
+		fmt.Printf("error updating degraded flag of node %s: %s\n", node.ID, err)
+		return
+	}
+
+	event := "recovered"
+	if degraded {
+		event = "degraded"
+		fmt.Printf("Node %s is degraded: p95 latency %s\n", node.ID, stats.P95)
+	}
+
+	if c.EventsCh != nil {
+		c.EventsCh <- PantheonEvent{
+			Event:  event,
+			NodeID: node.ID,
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package pantheon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestDegradedFlagClearedOnDeath(t *testing.T) {
+	server := miniredis.RunT(t)
+	p := newTestPantheon(t, server)
+	joinTestNodes(t, p, "node-a", "node-b")
+
+	if err := p.storage.UpdateNodeDegraded(p.ctx, "node-a", true); err != nil {
+		t.Fatal(err)
+	}
+
+	if node := setStoredNodeState(t, p, "node-a", MemberDead); node.Degraded {
+		t.Error("dead node is still degraded")
+	}
+
+	// A revived node is no longer degraded until its latency says so again
+	if node := setStoredNodeState(t, p, "node-a", MemberAlive); node.Degraded {
+		t.Error("revived node is still degraded")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := make([]time.Duration, 100)
+	for i := range values {
+		values[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	tests := []struct {
+		name   string
+		values []time.Duration
+		p      int
+		want   time.Duration
+	}{
+		{name: "empty", values: nil, p: 50, want: 0},
+		{name: "single value", values: []time.Duration{7}, p: 99, want: 7},
+		{name: "p50 of 100", values: values, p: 50, want: 50 * time.Millisecond},
+		{name: "p95 of 100", values: values, p: 95, want: 95 * time.Millisecond},
+		{name: "p99 of 100", values: values, p: 99, want: 99 * time.Millisecond},
+		{name: "nearest rank rounds up", values: []time.Duration{1, 2, 3}, p: 50, want: 2},
+		{name: "p95 of few values is the maximum", values: []time.Duration{1, 2, 3}, p: 95, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentile(tt.values, tt.p); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewNodeStats(t *testing.T) {
+	now := time.Now()
+	history := []ProbeRecord{
+		{At: now.Add(-10 * time.Minute), RTT: 40 * time.Millisecond, Success: false, Error: "old failure"},
+		{At: now.Add(-3 * time.Minute), RTT: 30 * time.Millisecond, Success: true},
+		{At: now.Add(-2 * time.Minute), RTT: 10 * time.Millisecond, Success: true},
+		{At: now.Add(-90 * time.Second), RTT: 500 * time.Millisecond, Success: false, Via: "node-b", Error: "indirect failure"},
+		{At: now.Add(-30 * time.Second), RTT: 20 * time.Millisecond, Success: true},
+		{At: now.Add(-10 * time.Second), RTT: time.Second, Success: false, Error: "timeout"},
+	}
+
+	stats := newNodeStats(history, []time.Duration{time.Minute, 5 * time.Minute, time.Hour}, now)
+
+	// Indirect probes count for the last error only
+	if stats.Probes != 5 {
+		t.Errorf("expected 5 direct probes, got %d", stats.Probes)
+	}
+	if stats.P50 != 20*time.Millisecond || stats.P95 != 30*time.Millisecond {
+		t.Errorf("unexpected percentiles p50=%s p95=%s", stats.P50, stats.P95)
+	}
+	if stats.LastError != "timeout" || !stats.LastErrorAt.Equal(now.Add(-10*time.Second)) {
+		t.Errorf("unexpected last error %q at %s", stats.LastError, stats.LastErrorAt)
+	}
+
+	want := []SuccessRate{
+		{Window: time.Minute, Probes: 2, Rate: 0.5},
+		{Window: 5 * time.Minute, Probes: 4, Rate: 0.75},
+		{Window: time.Hour, Probes: 5, Rate: 0.6},
+	}
+	for i, rate := range stats.SuccessRates {
+		if rate != want[i] {
+			t.Errorf("window %s: expected %+v, got %+v", want[i].Window, want[i], rate)
+		}
+	}
+}
+
+func TestGetNodeStats(t *testing.T) {
+	server := miniredis.RunT(t)
+	p := newTestPantheon(t, server)
+	joinTestNodes(t, p, "node-a")
+
+	now := time.Now()
+	for i := 1; i <= 3; i++ {
+		p.recordProbe("node-a", ProbeRecord{At: now, RTT: time.Duration(i) * time.Millisecond, Success: true})
+	}
+	p.recordProbe("node-a", ProbeRecord{At: now, RTT: time.Second, Error: "refused"})
+
+	stats, err := p.GetNodeStats("node-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The probe made by Join may be in the history as well
+	if stats.Probes < 4 || stats.LastError != "refused" || stats.P50 == 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if len(stats.SuccessRates) != 3 || stats.SuccessRates[0].Window != time.Minute {
+		t.Errorf("expected the default windows, got %+v", stats.SuccessRates)
+	}
+
+	if _, err := p.GetNodeStats("missing"); err == nil {
+		t.Error("expected an error for an unknown node")
+	}
+}
+
+func TestRevivedNodeNotDegradedByOldProbes(t *testing.T) {
+	server := miniredis.RunT(t)
+	p := newTestPantheon(t, server, func(o *Options) {
+		o.WithDegradedLatency(100 * time.Millisecond)
+	})
+	joinTestNodes(t, p, "node-a", "node-b")
+
+	slow := func(at time.Time) {
+		for i := 0; i < degradedMinProbes; i++ {
+			p.recordProbe("node-a", ProbeRecord{At: at, RTT: time.Second, Success: true})
+		}
+	}
+
+	// Slow probes before the node died
+	slow(time.Now().Add(-time.Minute))
+	setStoredNodeState(t, p, "node-a", MemberDead)
+	node := setStoredNodeState(t, p, "node-a", MemberAlive)
+
+	p.checkDegraded(node)
+	if node, _ := p.GetMember("node-a"); node.Degraded {
+		t.Fatal("revived node flagged for latencies from before it died")
+	}
+
+	// Slow probes after the revival count
+	slow(time.Now().Add(time.Second))
+	p.checkDegraded(node)
+	if node, _ := p.GetMember("node-a"); !node.Degraded {
+		t.Fatal("slow revived node was not flagged")
+	}
+}
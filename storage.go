@@ -2,10 +2,14 @@ package pantheon
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/fleetcontrolsio/pantheon/pkg/hashring"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -32,9 +36,13 @@ func (s *Storage) makeKey(parts ...string) string {
 // The node is identified by its ID.
 // The address and port are used to communicate with the node.
 // The path is the path on the node to make the heartbeat request to.
+// The weight is the relative capacity of the node in the hash ring.
+// The topology is the region, zone and rack the node runs in.
+// The health check is the name of the checker used to monitor the node.
+// The heartbeat policy overrides the cluster's heartbeat settings for the node.
 // The node is added with the state "alive".
 // The node is added with the current time as the joined_at and last_heartbeat times.
-func (s *Storage) AddNode(ctx context.Context, nodeID, address, path string, port int) error {
+func (s *Storage) AddNode(ctx context.Context, nodeID, address, path string, port, weight int, topology hashring.Topology, healthCheck string, policy HeartbeatPolicy) error {
 	key := s.makeKey("nodes", nodeID)
 
 	// Check if the node already exists
@@ -45,7 +53,7 @@ func (s *Storage) AddNode(ctx context.Context, nodeID, address, path string, por
 
 	if existing != nil {
 		// Update the existing node
-		return s.UpdateNode(ctx, nodeID, address, path, port)
+		return s.UpdateNode(ctx, nodeID, address, path, port, weight, topology, healthCheck, policy)
 	}
 
 	joinedAt := fmt.Sprintf("%d", time.Now().Unix())
@@ -59,7 +67,16 @@ func (s *Storage) AddNode(ctx context.Context, nodeID, address, path string, por
 		"last_heartbeat", joinedAt,
 		"hearbeat_count", "0",
 		"heartbeat_failure_count", "0",
-		"state", MemberAlive)
+		"state", MemberAlive,
+		"state_since_ms", strconv.FormatInt(time.Now().UnixMilli(), 10),
+		"weight", strconv.Itoa(weight),
+		"region", topology.Region,
+		"zone", topology.Zone,
+		"rack", topology.Rack,
+		"health_check", healthCheck,
+		"heartbeat_interval_ms", strconv.FormatInt(policy.Interval.Milliseconds(), 10),
+		"heartbeat_timeout_ms", strconv.FormatInt(policy.Timeout.Milliseconds(), 10),
+		"heartbeat_max_failures", strconv.Itoa(policy.MaxFailures))
 	if err := reply.Err(); err != nil {
 		return err
 	}
@@ -81,11 +98,39 @@ func (s *Storage) UpdateNodeHeartbeat(ctx context.Context, nodeID string) error
 	return nil
 }
 
-// UpdateNodeState updates the state of a node
-func (s *Storage) UpdateNodeState(ctx context.Context, nodeID string, state MemberState) error {
+// ClearDrainRequest forgets that a node was asked to drain
+func (s *Storage) ClearDrainRequest(ctx context.Context, nodeID string) error {
 	key := s.makeKey("nodes", nodeID)
+	if err := s.redis.HSet(ctx, key, "drain_requested", "false").Err(); err != nil {
+		return fmt.Errorf("error clearing drain request of node %s: %w", nodeID, err)
+	}
+	return nil
+}
+
+// UpdateNodeState moves a node from one state to another and records when it changed
+// Reviving a node, whether it comes back alive or draining, resets its
+// heartbeat failure and success counts.
+func (s *Storage) UpdateNodeState(ctx context.Context, nodeID string, from, state MemberState) error {
+	key := s.makeKey("nodes", nodeID)
+
+	fields := []interface{}{
+		"state", state,
+		"state_since_ms", strconv.FormatInt(time.Now().UnixMilli(), 10),
+	}
+	if state == MemberAlive || from == MemberDead {
+		fields = append(fields, "heartbeat_failure_count", "0", "heartbeat_success_count", "0")
+	}
+	// The drain intent outlives the draining state, e.g. when the node dies
+	if state == MemberDraining {
+		fields = append(fields, "drain_requested", "true")
+	}
+	// Latency only matters while the node answers; a revived node is only
+	// judged on probes made after it came back
+	if state == MemberDead {
+		fields = append(fields, "degraded", "false")
+	}
 
-	reply := s.redis.HSet(ctx, key, "state", state)
+	reply := s.redis.HSet(ctx, key, fields...)
 	if err := reply.Err(); err != nil {
 		return err
 	}
@@ -93,13 +138,21 @@ func (s *Storage) UpdateNodeState(ctx context.Context, nodeID string, state Memb
 	return nil
 }
 
-// UpdateNode updates the address and path of a node
-func (s *Storage) UpdateNode(ctx context.Context, nodeID, address, path string, port int) error {
+// UpdateNode updates the address, path, weight, topology, health check and heartbeat policy of a node
+func (s *Storage) UpdateNode(ctx context.Context, nodeID, address, path string, port, weight int, topology hashring.Topology, healthCheck string, policy HeartbeatPolicy) error {
 	key := s.makeKey("nodes", nodeID)
 	nodeAddress := fmt.Sprintf("%s:%d", address, port)
 	reply := s.redis.HSet(ctx, key,
 		"address", nodeAddress,
 		"path", path,
+		"weight", strconv.Itoa(weight),
+		"region", topology.Region,
+		"zone", topology.Zone,
+		"rack", topology.Rack,
+		"health_check", healthCheck,
+		"heartbeat_interval_ms", strconv.FormatInt(policy.Interval.Milliseconds(), 10),
+		"heartbeat_timeout_ms", strconv.FormatInt(policy.Timeout.Milliseconds(), 10),
+		"heartbeat_max_failures", strconv.Itoa(policy.MaxFailures),
 	)
 
 	if err := reply.Err(); err != nil {
@@ -109,6 +162,18 @@ func (s *Storage) UpdateNode(ctx context.Context, nodeID, address, path string,
 	return nil
 }
 
+// UpdateNodeWeight updates the weight of a node
+func (s *Storage) UpdateNodeWeight(ctx context.Context, nodeID string, weight int) error {
+	key := s.makeKey("nodes", nodeID)
+
+	reply := s.redis.HSet(ctx, key, "weight", strconv.Itoa(weight))
+	if err := reply.Err(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (s *Storage) IncrementHeartbeats(ctx context.Context, nodeID string) error {
 	key := s.makeKey("nodes", nodeID)
 
@@ -120,10 +185,37 @@ func (s *Storage) IncrementHeartbeats(ctx context.Context, nodeID string) error
 	return nil
 }
 
-func (s *Storage) IncrementHeartbeatFailures(ctx context.Context, nodeID string) error {
+// UpdateNodeDegraded records whether a node is degraded
+func (s *Storage) UpdateNodeDegraded(ctx context.Context, nodeID string, degraded bool) error {
+	key := s.makeKey("nodes", nodeID)
+
+	reply := s.redis.HSet(ctx, key, "degraded", strconv.FormatBool(degraded))
+	if err := reply.Err(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// IncrementHeartbeatFailures increments the failed heartbeat count of a node and returns the new count
+func (s *Storage) IncrementHeartbeatFailures(ctx context.Context, nodeID string) (int64, error) {
 	key := s.makeKey("nodes", nodeID)
 
-	reply := s.redis.HIncrBy(ctx, key, "hearbeat_failure_count", 1)
+	return s.redis.HIncrBy(ctx, key, "heartbeat_failure_count", 1).Result()
+}
+
+// IncrementHeartbeatSuccesses increments the consecutive successful heartbeat count of a node and returns the new count
+func (s *Storage) IncrementHeartbeatSuccesses(ctx context.Context, nodeID string) (int64, error) {
+	key := s.makeKey("nodes", nodeID)
+
+	return s.redis.HIncrBy(ctx, key, "heartbeat_success_count", 1).Result()
+}
+
+// ResetHeartbeatSuccesses resets the consecutive successful heartbeat count of a node
+func (s *Storage) ResetHeartbeatSuccesses(ctx context.Context, nodeID string) error {
+	key := s.makeKey("nodes", nodeID)
+
+	reply := s.redis.HSet(ctx, key, "heartbeat_success_count", "0")
 	if err := reply.Err(); err != nil {
 		return err
 	}
@@ -192,6 +284,38 @@ func (s *Storage) GetNode(ctx context.Context, nodeID string) (*Member, error) {
 		return nil, NewErrNodePropertyNotFound("state")
 	}
 
+	// Nodes stored before weights were introduced default to 1
+	weight := 1
+	if raw, ok := value["weight"]; ok {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight for node %s: %w", nodeID, err)
+		}
+		weight = parsed
+	}
+
+	// Nodes stored before health checks were selectable use HTTP
+	healthCheck := value["health_check"]
+	if healthCheck == "" {
+		healthCheck = HealthCheckHTTP
+	}
+
+	// Nodes stored before state changes were recorded have no state_since_ms
+	var stateSince time.Time
+	if raw := value["state_since_ms"]; raw != "" {
+		ms, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid state_since_ms for node %s: %w", nodeID, err)
+		}
+		stateSince = time.UnixMilli(ms)
+	}
+
+	// Nodes stored before heartbeat policies were introduced use the cluster defaults
+	policy, err := parseHeartbeatPolicy(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid heartbeat policy for node %s: %w", nodeID, err)
+	}
+
 	member := &Member{
 		ID:                nodeID,
 		Address:           address,
@@ -201,11 +325,55 @@ func (s *Storage) GetNode(ctx context.Context, nodeID string) (*Member, error) {
 		HeartbeatCount:    heartbeatCount,
 		HeartbeatFailures: heartbeatFailures,
 		State:             MemberState(state),
+		StateSince:        stateSince,
+		Weight:            weight,
+		// Topology fields are optional and empty when not set
+		Topology: hashring.Topology{
+			Region: value["region"],
+			Zone:   value["zone"],
+			Rack:   value["rack"],
+		},
+		HealthCheck:    healthCheck,
+		Heartbeat:      policy,
+		Degraded:       value["degraded"] == "true",
+		DrainRequested: value["drain_requested"] == "true",
 	}
 
 	return member, nil
 }
 
+// parseHeartbeatPolicy reads a heartbeat policy from the fields of a node hash
+// Missing fields are left at zero.
+func parseHeartbeatPolicy(value map[string]string) (HeartbeatPolicy, error) {
+	var policy HeartbeatPolicy
+
+	if raw := value["heartbeat_interval_ms"]; raw != "" {
+		ms, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return policy, err
+		}
+		policy.Interval = time.Duration(ms) * time.Millisecond
+	}
+
+	if raw := value["heartbeat_timeout_ms"]; raw != "" {
+		ms, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return policy, err
+		}
+		policy.Timeout = time.Duration(ms) * time.Millisecond
+	}
+
+	if raw := value["heartbeat_max_failures"]; raw != "" {
+		maxFailures, err := strconv.Atoi(raw)
+		if err != nil {
+			return policy, err
+		}
+		policy.MaxFailures = maxFailures
+	}
+
+	return policy, nil
+}
+
 // GetNodes retrieves all nodes from the cluster
 func (s *Storage) GetNodes(ctx context.Context) ([]Member, error) {
 	pattern := s.makeKey("nodes", "*")
@@ -231,6 +399,364 @@ func (s *Storage) GetNodes(ctx context.Context) ([]Member, error) {
 	return members, nil
 }
 
+// saveRingScript stores a ring only if its epoch is newer than the stored ring's epoch
+// KEYS[1] is the ring, KEYS[2] its epoch; ARGV[1] is the new epoch, ARGV[2] the ring.
+const saveRingScript = `
+local current = tonumber(redis.call('GET', KEYS[2]) or '0')
+if current >= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2])
+redis.call('SET', KEYS[2], ARGV[1])
+return 1
+`
+
+// SaveRing stores the serialized hash ring if its epoch is newer than the stored one
+// It reports whether the ring was stored; false means another process stored a
+// ring with the same or a later epoch first.
+func (s *Storage) SaveRing(ctx context.Context, data []byte, epoch uint64) (bool, error) {
+	keys := []string{s.makeKey("ring"), s.makeKey("ring", "epoch")}
+
+	saved, err := s.redis.Eval(ctx, saveRingScript, keys, strconv.FormatUint(epoch, 10), data).Int()
+	if err != nil {
+		return false, fmt.Errorf("error storing hash ring: %w", err)
+	}
+
+	return saved == 1, nil
+}
+
+// GetRingEpoch returns the epoch of the stored hash ring, 0 if none has been stored
+func (s *Storage) GetRingEpoch(ctx context.Context) (uint64, error) {
+	key := s.makeKey("ring", "epoch")
+
+	epoch, err := s.redis.Get(ctx, key).Uint64()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("error getting hash ring epoch: %w", err)
+	}
+
+	return epoch, nil
+}
+
+// GetRing retrieves the serialized hash ring
+// It returns nil if no ring has been stored.
+func (s *Storage) GetRing(ctx context.Context) ([]byte, error) {
+	key := s.makeKey("ring")
+
+	data, err := s.redis.Get(ctx, key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting hash ring: %w", err)
+	}
+
+	return data, nil
+}
+
+// keyMappingBatchSize is the number of keys fetched per MGET
+const keyMappingBatchSize = 1000
+
+// GetKeyMappings returns the node each key is mapped to, in the order of keys
+// Unmapped keys map to an empty string. Keys are fetched with MGET in batches.
+func (s *Storage) GetKeyMappings(ctx context.Context, keys []string) ([]string, error) {
+	mappings := make([]string, 0, len(keys))
+
+	for start := 0; start < len(keys); start += keyMappingBatchSize {
+		end := min(start+keyMappingBatchSize, len(keys))
+
+		keyMapKeys := make([]string, 0, end-start)
+		for _, key := range keys[start:end] {
+			keyMapKeys = append(keyMapKeys, s.makeKey("keymap", key))
+		}
+
+		values, err := s.redis.MGet(ctx, keyMapKeys...).Result()
+		if err != nil && err != redis.Nil {
+			return nil, fmt.Errorf("error getting key mappings: %w", err)
+		}
+
+		for i := range keyMapKeys {
+			nodeID := ""
+			if i < len(values) {
+				if value, ok := values[i].(string); ok {
+					nodeID = value
+				}
+			}
+			mappings = append(mappings, nodeID)
+		}
+	}
+
+	return mappings, nil
+}
+
+// SetKeyMappings maps each key to its node in a single pipeline
+// The keys are also added to their node's key set.
+func (s *Storage) SetKeyMappings(ctx context.Context, mappings map[string]string) error {
+	if len(mappings) == 0 {
+		return nil
+	}
+
+	pipe := s.redis.Pipeline()
+	for key, nodeID := range mappings {
+		pipe.Set(ctx, s.makeKey("keymap", key), nodeID, 0)
+		pipe.SAdd(ctx, s.makeKey("nodekeys", nodeID), key)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("error storing key mappings: %w", err)
+	}
+
+	return nil
+}
+
+// AddProbeRecord appends a probe to a node's heartbeat history, keeping the last limit probes
+func (s *Storage) AddProbeRecord(ctx context.Context, nodeID string, record ProbeRecord, limit int) error {
+	key := s.makeKey("history", nodeID)
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	pipe := s.redis.Pipeline()
+	pipe.RPush(ctx, key, data)
+	pipe.LTrim(ctx, key, int64(-limit), -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("error storing probe record: %w", err)
+	}
+
+	return nil
+}
+
+// GetProbeHistory retrieves a node's heartbeat history, oldest first
+func (s *Storage) GetProbeHistory(ctx context.Context, nodeID string) ([]ProbeRecord, error) {
+	key := s.makeKey("history", nodeID)
+
+	entries, err := s.redis.LRange(ctx, key, 0, -1).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("error getting probe history: %w", err)
+	}
+
+	records := make([]ProbeRecord, 0, len(entries))
+	for _, entry := range entries {
+		var record ProbeRecord
+		if err := json.Unmarshal([]byte(entry), &record); err != nil {
+			return nil, fmt.Errorf("invalid probe record for node %s: %w", nodeID, err)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// RenewLease sets or refreshes the lease of a node, expiring after ttl
+func (s *Storage) RenewLease(ctx context.Context, nodeID string, ttl time.Duration) error {
+	key := s.makeKey("leases", nodeID)
+
+	renewedAt := fmt.Sprintf("%d", time.Now().Unix())
+	if err := s.redis.Set(ctx, key, renewedAt, ttl).Err(); err != nil {
+		return fmt.Errorf("error renewing lease: %w", err)
+	}
+
+	return nil
+}
+
+// HasLease reports whether a node holds a lease that has not expired
+func (s *Storage) HasLease(ctx context.Context, nodeID string) (bool, error) {
+	key := s.makeKey("leases", nodeID)
+
+	if err := s.redis.Get(ctx, key).Err(); err != nil {
+		if err == redis.Nil {
+			return false, nil
+		}
+		return false, fmt.Errorf("error getting lease: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetHeartbeatWindow retrieves the heartbeat timings kept for a node by the phi accrual detector
+// The window is stored in the node hash; a node without one gets an empty window.
+func (s *Storage) GetHeartbeatWindow(ctx context.Context, nodeID string) (*HeartbeatWindow, error) {
+	key := s.makeKey("nodes", nodeID)
+
+	value, err := s.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error getting heartbeat window: %w", err)
+	}
+
+	window := &HeartbeatWindow{}
+
+	if raw := value["heartbeat_last_arrival_us"]; raw != "" {
+		micros, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid heartbeat_last_arrival_us for node %s: %w", nodeID, err)
+		}
+		window.LastArrival = time.UnixMicro(micros)
+	}
+
+	if window.Intervals, err = parseDurations(value["heartbeat_intervals_us"]); err != nil {
+		return nil, fmt.Errorf("invalid heartbeat_intervals_us for node %s: %w", nodeID, err)
+	}
+
+	if window.RTTs, err = parseDurations(value["heartbeat_rtts_us"]); err != nil {
+		return nil, fmt.Errorf("invalid heartbeat_rtts_us for node %s: %w", nodeID, err)
+	}
+
+	return window, nil
+}
+
+// SaveHeartbeatWindow stores the heartbeat timings of a node in its node hash
+func (s *Storage) SaveHeartbeatWindow(ctx context.Context, nodeID string, window *HeartbeatWindow) error {
+	key := s.makeKey("nodes", nodeID)
+
+	reply := s.redis.HSet(ctx, key,
+		"heartbeat_last_arrival_us", strconv.FormatInt(window.LastArrival.UnixMicro(), 10),
+		"heartbeat_intervals_us", formatDurations(window.Intervals),
+		"heartbeat_rtts_us", formatDurations(window.RTTs))
+	if err := reply.Err(); err != nil {
+		return fmt.Errorf("error storing heartbeat window: %w", err)
+	}
+
+	return nil
+}
+
+// formatDurations encodes durations as comma-separated microseconds
+func formatDurations(durations []time.Duration) string {
+	values := make([]string, len(durations))
+	for i, duration := range durations {
+		values[i] = strconv.FormatInt(duration.Microseconds(), 10)
+	}
+	return strings.Join(values, ",")
+}
+
+// parseDurations decodes comma-separated microseconds
+func parseDurations(raw string) ([]time.Duration, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	fields := strings.Split(raw, ",")
+	durations := make([]time.Duration, len(fields))
+	for i, field := range fields {
+		micros, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		durations[i] = time.Duration(micros) * time.Microsecond
+	}
+	return durations, nil
+}
+
+// GetPartitionTable retrieves the owner of every stored partition
+// Partitions that have never been assigned are missing from the map.
+func (s *Storage) GetPartitionTable(ctx context.Context) (map[int]string, error) {
+	key := s.makeKey("partitions")
+
+	fields, err := s.redis.HGetAll(ctx, key).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("error getting partition table: %w", err)
+	}
+
+	table := make(map[int]string, len(fields))
+	for field, nodeID := range fields {
+		partition, err := strconv.Atoi(field)
+		if err != nil {
+			continue
+		}
+		table[partition] = nodeID
+	}
+
+	return table, nil
+}
+
+// GetPartitionOwner retrieves the owner of a partition
+// It returns an empty string if the partition has never been assigned.
+func (s *Storage) GetPartitionOwner(ctx context.Context, partition int) (string, error) {
+	key := s.makeKey("partitions")
+
+	nodeID, err := s.redis.HGet(ctx, key, strconv.Itoa(partition)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", nil
+		}
+		return "", fmt.Errorf("error getting partition owner: %w", err)
+	}
+
+	return nodeID, nil
+}
+
+// SetPartitionOwners assigns the given partitions to their nodes
+func (s *Storage) SetPartitionOwners(ctx context.Context, owners map[int]string) error {
+	if len(owners) == 0 {
+		return nil
+	}
+
+	key := s.makeKey("partitions")
+
+	fields := make([]interface{}, 0, len(owners)*2)
+	for partition, nodeID := range owners {
+		fields = append(fields, strconv.Itoa(partition), nodeID)
+	}
+
+	if err := s.redis.HSet(ctx, key, fields...).Err(); err != nil {
+		return fmt.Errorf("error storing partition table: %w", err)
+	}
+
+	return nil
+}
+
+// GetKeyReplicas returns the stored replica set of a key, empty if none is stored
+func (s *Storage) GetKeyReplicas(ctx context.Context, key string) ([]string, error) {
+	replicas, err := s.redis.LRange(ctx, s.makeKey("keyreplicas", key), 0, -1).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("error getting replicas for key %s: %w", key, err)
+	}
+
+	return replicas, nil
+}
+
+// SetKeyReplicas replaces the stored replica set of a key
+// Each node keeps an index of the keys whose replica set includes it, so the
+// key is removed from the index of previous replicas and added to the new ones.
+func (s *Storage) SetKeyReplicas(ctx context.Context, key string, previous, replicas []string) error {
+	replicasKey := s.makeKey("keyreplicas", key)
+
+	pipe := s.redis.Pipeline()
+	pipe.Del(ctx, replicasKey)
+	for _, nodeID := range previous {
+		if !slices.Contains(replicas, nodeID) {
+			pipe.SRem(ctx, s.makeKey("nodereplicas", nodeID), key)
+		}
+	}
+	if len(replicas) > 0 {
+		values := make([]interface{}, len(replicas))
+		for i, nodeID := range replicas {
+			values[i] = nodeID
+			pipe.SAdd(ctx, s.makeKey("nodereplicas", nodeID), key)
+		}
+		pipe.RPush(ctx, replicasKey, values...)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("error storing replicas: %w", err)
+	}
+
+	return nil
+}
+
+// GetNodeReplicaKeys returns up to count random keys whose replica set includes the node
+func (s *Storage) GetNodeReplicaKeys(ctx context.Context, nodeID string, count int) ([]string, error) {
+	keys, err := s.redis.SRandMemberN(ctx, s.makeKey("nodereplicas", nodeID), int64(count)).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("error getting replicated keys of node %s: %w", nodeID, err)
+	}
+
+	return keys, nil
+}
+
 // RemoveNode removes a node from the cluster
 func (s *Storage) RemoveNode(ctx context.Context, nodeID string) error {
 	// Get node keys before deleting the node
@@ -248,11 +774,16 @@ func (s *Storage) RemoveNode(ctx context.Context, nodeID string) error {
 		}
 	}
 
-	// Remove the node keys set
-	if err := s.redis.Del(ctx, nodeKeysKey).Err(); err != nil {
+	// Remove the node keys set and replica set index
+	if err := s.redis.Del(ctx, nodeKeysKey, s.makeKey("nodereplicas", nodeID)).Err(); err != nil {
 		return fmt.Errorf("error removing node keys: %w", err)
 	}
 
+	// Remove the node's lease and heartbeat history
+	if err := s.redis.Del(ctx, s.makeKey("leases", nodeID), s.makeKey("history", nodeID)).Err(); err != nil {
+		return fmt.Errorf("error removing lease: %w", err)
+	}
+
 	// Remove the node
 	key := s.makeKey("nodes", nodeID)
 	reply := s.redis.Del(ctx, key)